@@ -0,0 +1,92 @@
+package bson
+
+import "reflect"
+
+// DocumentType defines the Go type of documents decoded into interface values.
+type DocumentType int
+
+const (
+	// DocumentMap decodes documents as map[string]any, this is the default.
+	DocumentMap DocumentType = iota
+
+	// DocumentD decodes documents as [D] preserving order of elements.
+	DocumentD
+
+	// DocumentM decodes documents as [M].
+	DocumentM
+
+	// DocumentRaw decodes documents as [RawObject].
+	DocumentRaw
+)
+
+// ArrayType defines the Go type of arrays decoded into interface values.
+type ArrayType int
+
+const (
+	// ArraySlice decodes arrays as []any, this is the default.
+	ArraySlice ArrayType = iota
+
+	// ArrayA decodes arrays as [A].
+	ArrayA
+)
+
+// SetDocumentType sets the type of documents decoded into interface values,
+// including the top-level document decoded into *any.
+func (dec *Decoder) SetDocumentType(t DocumentType) {
+	dec.docType = t
+}
+
+// SetArrayType sets the type of arrays decoded into interface values.
+func (dec *Decoder) SetArrayType(t ArrayType) {
+	dec.arrType = t
+}
+
+// decodeAnyDocument decodes the document according to the document type.
+func (ds *decodeState) decodeAnyDocument(data []byte) (any, error) {
+	switch ds.docType {
+	case DocumentD:
+		d := D{}
+		if err := ds.readD(data, &d); err != nil {
+			return nil, err
+		}
+		return d, nil
+
+	case DocumentRaw:
+		return RawObject(append([]byte(nil), data...)), nil
+
+	default:
+		m := make(map[string]any)
+		if err := ds.decodeMap(data, reflect.ValueOf(m)); err != nil {
+			return nil, err
+		}
+		if ds.docType == DocumentM {
+			return M(m), nil
+		}
+		return m, nil
+	}
+}
+
+// decodeAnyArray decodes the array according to the array type.
+func (ds *decodeState) decodeAnyArray(data []byte) (any, error) {
+	s := make([]any, 0)
+	if err := ds.decodeSlice(data, &s); err != nil {
+		return nil, err
+	}
+	return ds.array(s), nil
+}
+
+// array returns s as the array type.
+func (ds *decodeState) array(s []any) any {
+	if ds.arrType == ArrayA {
+		return A(s)
+	}
+	return s
+}
+
+// appendArray appends the value to the array of the array type.
+func (ds *decodeState) appendArray(a, val any) any {
+	if s, ok := a.(A); ok {
+		return append(s, val)
+	}
+	return append(a.([]any), val)
+}
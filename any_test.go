@@ -0,0 +1,88 @@
+package bson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDecodeAny(t *testing.T) {
+	raw := must(Marshal(D{
+		{"b", int32(1)},
+		{"a", D{{"y", "x"}, {"x", A{int32(1), D{{"z", true}}}}}},
+	}))
+
+	var v any
+	mustOk(t, Unmarshal(raw, &v))
+	mustDeepEqual(t, v, map[string]any{
+		"b": int32(1),
+		"a": map[string]any{"y": "x", "x": []any{int32(1), map[string]any{"z": true}}},
+	})
+
+	testCases := []struct {
+		docType DocumentType
+		arrType ArrayType
+		want    any
+	}{
+		{
+			docType: DocumentD,
+			arrType: ArrayA,
+			want: D{
+				{"b", int32(1)},
+				{"a", D{{"y", "x"}, {"x", A{int32(1), D{{"z", true}}}}}},
+			},
+		},
+		{
+			docType: DocumentM,
+			arrType: ArraySlice,
+			want: M{
+				"b": int32(1),
+				"a": M{"y": "x", "x": []any{int32(1), M{"z": true}}},
+			},
+		},
+		{
+			docType: DocumentRaw,
+			want:    RawObject(raw),
+		},
+	}
+
+	for _, tc := range testCases {
+		dec := NewDecodeBytes(raw)
+		dec.SetDocumentType(tc.docType)
+		dec.SetArrayType(tc.arrType)
+
+		var v any
+		mustOk(t, dec.Decode(&v))
+		mustDeepEqual(t, v, tc.want)
+	}
+
+	type record struct {
+		A any `bson:"a"`
+		M M   `bson:"m"`
+		S []any
+	}
+	dec := NewDecodeBytes(must(Marshal(D{
+		{"a", D{{"x", int32(1)}}},
+		{"m", D{{"y", D{{"z", int32(2)}}}}},
+		{"s", A{D{}}},
+	})))
+	dec.SetDocumentType(DocumentD)
+	dec.SetArrayType(ArrayA)
+	var r record
+	mustOk(t, dec.Decode(&r))
+	mustDeepEqual(t, r, record{
+		A: D{{"x", int32(1)}},
+		M: M{"y": D{{"z", int32(2)}}},
+		S: []any{D{}},
+	})
+
+	dec = NewDecodeBytes(must(Marshal(D{{"a", int32(1)}, {"a", int32(2)}})))
+	dec.SetDocumentType(DocumentD)
+	dec.SetArrayType(ArrayA)
+	dec.SetDuplicateKeyPolicy(DuplicateCollect)
+	mustOk(t, dec.Decode(&v))
+	mustDeepEqual(t, v, D{{"a", A{int32(1), int32(2)}}})
+
+	var s interface{ String() string }
+	err := Unmarshal(raw, &s)
+	mustEqual(t, errors.Is(err, ErrTypeMismatch), true)
+}
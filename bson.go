@@ -2,6 +2,8 @@ package bson
 
 import (
 	"bytes"
+	"fmt"
+	"io"
 	"sort"
 	"strconv"
 )
@@ -47,14 +49,38 @@ type Unmarshaler interface {
 
 // Unmarshal parses the BSON data and stores the result
 // in the value pointed to by v.
+//
+// The data must contain exactly one document: empty data returns [io.ErrUnexpectedEOF]
+// and bytes after the document return a [DecodeError] wrapping [ErrInvalidInput].
+// Use [Decoder] to read a stream of documents.
 func Unmarshal(data []byte, v any) error {
 	d := NewDecodeBytes(data)
 	if err := d.Decode(v); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
 		return err
 	}
+	if d.off < len(data) {
+		return &DecodeError{
+			Offset: d.off,
+			Err:    fmt.Errorf("%w: %d trailing bytes after the document", ErrInvalidInput, len(data)-d.off),
+		}
+	}
 	return nil
 }
 
+// UnmarshalAs parses the BSON data and returns the result as T.
+//
+// Example:
+//
+//	doc, err := bson.UnmarshalAs[bson.D](data)
+func UnmarshalAs[T any](data []byte) (T, error) {
+	var v T
+	err := Unmarshal(data, &v)
+	return v, err
+}
+
 // A is a BSON array.
 //
 // Example:
@@ -62,10 +88,12 @@ func Unmarshal(data []byte, v any) error {
 //	bson.A{"hello", "world", 3.14159, bson.D{{"foo", 12345}}}
 type A []any
 
+// AsD returns the array as a document with indices as keys.
+// Values are not copied.
 func (a A) AsD() D {
 	d := make(D, len(a))
 	for i, v := range a {
-		d[i] = e{K: strconv.Itoa(i), V: v}
+		d[i] = E{Key: strconv.Itoa(i), Value: v}
 	}
 	return d
 }
@@ -75,22 +103,23 @@ func (a A) AsD() D {
 // Example usage:
 //
 //	bson.D{{"hello", "world"}, {"foo", "bar"}, {"pi", 3.14159}}
-type D []e
+type D []E
 
-// e represents a BSON element for a D. It is usually used inside a D.
-type e struct {
-	K string
-	V any
+// E represents a BSON element for a D. It is usually used inside a D.
+type E struct {
+	Key   string
+	Value any
 }
 
 func (d D) Len() int           { return len(d) }
-func (d D) Less(i, j int) bool { return d[i].K < d[j].K }
+func (d D) Less(i, j int) bool { return d[i].Key < d[j].Key }
 func (d D) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
 
+// AsM returns the document as M, nested values are not converted, see [D.ToMDeep].
 func (d D) AsM() M {
 	m := make(M, len(d))
 	for _, pair := range d {
-		m[pair.K] = pair.V
+		m[pair.Key] = pair.Value
 	}
 	return m
 }
@@ -102,11 +131,12 @@ func (d D) AsM() M {
 //	bson.M{"hello": "world", "foo": "bar", "pi": 3.14159}
 type M map[string]any
 
+// AsD returns the document as D sorted by keys, nested values are not converted, see [M.ToDDeep].
 func (m M) AsD() D {
 	d := make(D, len(m))
 	i := 0
 	for k, v := range m {
-		d[i] = e{K: k, V: v}
+		d[i] = E{Key: k, Value: v}
 		i++
 	}
 
@@ -119,3 +149,9 @@ type RawArray []byte
 
 // RawObject represents a raw object which will be encoded or decoded as is.
 type RawObject []byte
+
+// RawValue represents a raw element value of the given type which will be encoded as is.
+type RawValue struct {
+	Type Type
+	Data []byte
+}
@@ -0,0 +1,443 @@
+package bson
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cristalhq/bson/bsonproto"
+)
+
+// typeOrder is a rank of a value in MongoDB comparison order.
+type typeOrder int
+
+const (
+	orderMinKey typeOrder = iota + 1
+	orderUndefined
+	orderNull
+	orderNumber
+	orderString
+	orderDocument
+	orderArray
+	orderBinary
+	orderObjectID
+	orderBool
+	orderDate
+	orderTimestamp
+	orderRegex
+	orderDBPointer
+	orderCode
+	orderCodeWithScope
+	orderMaxKey
+	orderInvalid // a value which cannot be represented in BSON.
+)
+
+// Compare returns an integer comparing two BSON values.
+// The result will be 0 if a == b, -1 if a < b, and +1 if a > b.
+//
+// Values of different types are ordered as MongoDB does:
+//
+//	MinKey < Undefined < Null < Numbers < String, Symbol < Object < Array < BinData < ObjectID < Bool < Date < Timestamp < Regex <
+//	DBPointer < JavaScript < JavaScript with scope < MaxKey
+//
+// Numbers (ints, floats and [bsonproto.Decimal128]) are compared by value regardless of the type, NaN is less than any other number.
+// Documents and arrays are compared element by element.
+// [RawObject] and [RawArray] are decoded before comparison.
+// Deprecated types are given as [RawValue] and compared by their encoding.
+//
+// Values which cannot be represented in BSON, like channels or malformed raw documents,
+// are greater than MaxKey and equal to each other.
+func Compare(a, b any) int {
+	oa, va := canonicalOrder(a)
+	ob, vb := canonicalOrder(b)
+	if oa != ob {
+		return cmpInt(int(oa), int(ob))
+	}
+
+	switch oa {
+	case orderMinKey, orderUndefined, orderNull, orderMaxKey, orderInvalid:
+		return 0
+	case orderNumber:
+		return compareNumbers(va, vb)
+	case orderString:
+		return strings.Compare(va.(string), vb.(string))
+	case orderDocument:
+		return compareDocs(va.(D), vb.(D))
+	case orderArray:
+		return compareArrays(va.(A), vb.(A))
+	case orderBinary:
+		x, y := va.(bsonproto.Binary), vb.(bsonproto.Binary)
+		if c := cmpInt(len(x.B), len(y.B)); c != 0 {
+			return c
+		}
+		if c := cmpInt(int(x.Subtype), int(y.Subtype)); c != 0 {
+			return c
+		}
+		return bytes.Compare(x.B, y.B)
+	case orderObjectID:
+		return va.(ObjectID).Compare(vb.(ObjectID))
+	case orderBool:
+		x, y := va.(bool), vb.(bool)
+		switch {
+		case x == y:
+			return 0
+		case !x:
+			return -1
+		default:
+			return 1
+		}
+	case orderDate:
+		x, y := va.(time.Time), vb.(time.Time)
+		switch {
+		case x.Before(y):
+			return -1
+		case x.After(y):
+			return 1
+		default:
+			return 0
+		}
+	case orderTimestamp:
+		return va.(Timestamp).Compare(vb.(Timestamp))
+	case orderRegex:
+		x, y := va.(Regex), vb.(Regex)
+		if c := strings.Compare(x.Pattern, y.Pattern); c != 0 {
+			return c
+		}
+		return strings.Compare(x.Options, y.Options)
+	case orderDBPointer, orderCode, orderCodeWithScope:
+		return bytes.Compare(va.(RawValue).Data, vb.(RawValue).Data)
+	default:
+		panic("unreachable")
+	}
+}
+
+func compareDocs(a, b D) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		oa, _ := canonicalOrder(a[i].Value)
+		ob, _ := canonicalOrder(b[i].Value)
+		if c := cmpInt(int(oa), int(ob)); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a[i].Key, b[i].Key); c != 0 {
+			return c
+		}
+		if c := Compare(a[i].Value, b[i].Value); c != 0 {
+			return c
+		}
+	}
+	return cmpInt(len(a), len(b))
+}
+
+func compareArrays(a, b A) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return cmpInt(len(a), len(b))
+}
+
+// canonicalOrder returns the comparison order of v and the canonical value,
+// orderInvalid if v cannot be canonicalized.
+func canonicalOrder(v any) (typeOrder, any) {
+	order, val, err := canonicalValue(v)
+	if err != nil {
+		return orderInvalid, nil
+	}
+	return order, val
+}
+
+// canonicalValue returns the comparison order of v and the value in a canonical form:
+// int64, float64 or Decimal128 for numbers, D for documents, A for arrays, Binary for binary data.
+// Nested values are not checked, see [checkCanonical].
+func canonicalValue(v any) (typeOrder, any, error) {
+	switch v := v.(type) {
+	case nil:
+		return orderNull, nil, nil
+	case MinKey:
+		return orderMinKey, v, nil
+	case MaxKey:
+		return orderMaxKey, v, nil
+
+	case int:
+		return orderNumber, int64(v), nil
+	case int8:
+		return orderNumber, int64(v), nil
+	case int16:
+		return orderNumber, int64(v), nil
+	case int32:
+		return orderNumber, int64(v), nil
+	case int64:
+		return orderNumber, v, nil
+	case uint:
+		return orderNumber, uint64(v), nil
+	case uint8:
+		return orderNumber, int64(v), nil
+	case uint16:
+		return orderNumber, int64(v), nil
+	case uint32:
+		return orderNumber, int64(v), nil
+	case uint64:
+		return orderNumber, v, nil
+	case float32:
+		return orderNumber, float64(v), nil
+	case float64:
+		return orderNumber, v, nil
+	case bsonproto.Decimal128:
+		return orderNumber, v, nil
+
+	case string:
+		return orderString, v, nil
+
+	case D:
+		return orderDocument, v, nil
+	case M:
+		return orderDocument, v.AsD(), nil
+	case map[string]any:
+		return orderDocument, M(v).AsD(), nil
+	case RawObject:
+		d, err := rawToD(v, 1)
+		return orderDocument, d, err
+
+	case A:
+		return orderArray, v, nil
+	case []any:
+		return orderArray, A(v), nil
+	case RawArray:
+		a, err := rawToA(v, 1)
+		return orderArray, a, err
+	case RawValue:
+		val, err := decodeRawElement(v)
+		if err != nil {
+			return 0, nil, err
+		}
+		raw, ok := val.(RawValue)
+		if !ok {
+			return canonicalValue(val)
+		}
+		switch raw.Type {
+		case TypeUndefined:
+			return orderUndefined, nil, nil
+		case TypeSymbol:
+			var ds decodeState
+			s, err := ds.str(raw.Data[4:])
+			return orderString, s, err
+		case TypeDBPointer:
+			return orderDBPointer, raw, nil
+		case TypeCodeWithScope:
+			return orderCode, raw, nil
+		default:
+			return orderCodeWithScope, raw, nil
+		}
+
+	case []byte:
+		return orderBinary, bsonproto.Binary{B: v}, nil
+	case bsonproto.Binary:
+		return orderBinary, v, nil
+	case ObjectID:
+		return orderObjectID, v, nil
+	case bool:
+		return orderBool, v, nil
+	case time.Time:
+		return orderDate, v, nil
+	case Timestamp:
+		return orderTimestamp, v, nil
+	case Regex:
+		return orderRegex, v, nil
+	}
+
+	return canonicalReflect(reflect.ValueOf(v))
+}
+
+func canonicalReflect(v reflect.Value) (typeOrder, any, error) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return orderNull, nil, nil
+		}
+		return canonicalValue(v.Elem().Interface())
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return orderNumber, v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return orderNumber, v.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return orderNumber, v.Float(), nil
+	case reflect.String:
+		return orderString, v.String(), nil
+	case reflect.Bool:
+		return orderBool, v.Bool(), nil
+
+	case reflect.Struct:
+		info, err := getStruct(v)
+		if err != nil {
+			return 0, nil, err
+		}
+		doc := info.asDoc(v)
+		d := make(D, len(doc))
+		for i, pair := range doc {
+			d[i] = E{Key: pair.Key, Value: pair.Val}
+		}
+		return orderDocument, d, nil
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		d := make(D, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			d = append(d, E{Key: key.String(), Value: v.MapIndex(key).Interface()})
+		}
+		sort.Sort(d)
+		return orderDocument, d, nil
+
+	case reflect.Slice, reflect.Array:
+		a := make(A, v.Len())
+		for i := range a {
+			a[i] = v.Index(i).Interface()
+		}
+		return orderArray, a, nil
+	}
+	return 0, nil, &UnsupportedValueError{Type: v.Type(), Reason: "cannot be compared"}
+}
+
+// decodeRawElement decodes the value as an element of a document,
+// so the reader checks that the data matches the type.
+func decodeRawElement(v RawValue) (any, error) {
+	n := 4 + 2 + len(v.Data) + 1
+	doc := []byte{byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24), byte(v.Type), 0}
+	doc = append(append(doc, v.Data...), 0)
+
+	d, err := rawToD(doc, 0)
+	switch {
+	case err != nil:
+		return nil, err
+	case len(d) != 1:
+		return nil, fmt.Errorf("%w: raw value of type %s has extra data", ErrInvalidInput, v.Type)
+	}
+	return d[0].Value, nil
+}
+
+// checkCanonical reports an error if v or any nested value cannot be canonicalized.
+func checkCanonical(v any, depth int) error {
+	order, val, err := canonicalValue(v)
+	switch {
+	case err != nil:
+		return err
+	case (order == orderDocument || order == orderArray) && depth > DefaultMaxDepth:
+		return depthError(DefaultMaxDepth)
+	case order == orderDocument:
+		for _, elem := range val.(D) {
+			if err := checkCanonical(elem.Value, depth+1); err != nil {
+				return withKey(err, elem.Key)
+			}
+		}
+	case order == orderArray:
+		for i, elem := range val.(A) {
+			if err := checkCanonical(elem, depth+1); err != nil {
+				return withKey(err, strconv.Itoa(i))
+			}
+		}
+	}
+	return nil
+}
+
+func compareNumbers(a, b any) int {
+	switch a := a.(type) {
+	case int64:
+		if b, ok := b.(int64); ok {
+			return cmpInt64(a, b)
+		}
+	case float64:
+		if b, ok := b.(float64); ok && !math.IsNaN(a) && !math.IsNaN(b) {
+			switch {
+			case a < b:
+				return -1
+			case a > b:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+
+	fa, nanA := bigNumber(a)
+	fb, nanB := bigNumber(b)
+	switch {
+	case nanA && nanB:
+		return 0
+	case nanA:
+		return -1
+	case nanB:
+		return 1
+	default:
+		return fa.Cmp(fb)
+	}
+}
+
+// bigNumber returns a canonical number as big.Float, reports whether it's NaN.
+func bigNumber(v any) (*big.Float, bool) {
+	switch v := v.(type) {
+	case int64:
+		return new(big.Float).SetInt64(v), false
+	case uint64:
+		return new(big.Float).SetUint64(v), false
+	case float64:
+		if math.IsNaN(v) {
+			return nil, true
+		}
+		return new(big.Float).SetFloat64(v), false
+	case bsonproto.Decimal128:
+		return decimalToBig(v)
+	default:
+		panic("unreachable")
+	}
+}
+
+// decimalToBig converts IEEE 754-2008 decimal128 (BID encoding) into big.Float.
+func decimalToBig(d bsonproto.Decimal128) (*big.Float, bool) {
+	coef, exp, ok := decimalParts(d)
+	switch {
+	case !ok && (d.H>>58)&0x1f == 0x1f:
+		return nil, true
+	case !ok:
+		return new(big.Float).SetInf(d.H>>63 == 1), false
+	}
+
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(exp))), nil)
+	if exp >= 0 {
+		return new(big.Float).SetInt(coef.Mul(coef, scale)), false
+	}
+
+	num := new(big.Float).SetInt(coef)
+	den := new(big.Float).SetInt(scale)
+	return new(big.Float).SetPrec(512).Quo(num, den), false
+}
+
+func cmpInt(a, b int) int {
+	return cmpInt64(int64(a), int64(b))
+}
+
+func cmpInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,179 @@
+package bson
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/cristalhq/bson/bsonproto"
+)
+
+func TestCompareTypeOrder(t *testing.T) {
+	ordered := []any{
+		MinKey{},
+		nil,
+		int32(1),
+		"str",
+		D{{"a", int32(1)}},
+		A{int32(1)},
+		[]byte{1},
+		ObjectID{1},
+		false,
+		time.Unix(1, 0),
+		Timestamp(1),
+		Regex{Pattern: "a"},
+		MaxKey{},
+	}
+
+	for i := range ordered {
+		for j := range ordered {
+			mustEqual(t, Compare(ordered[i], ordered[j]), cmpInt(i, j))
+		}
+	}
+}
+
+func TestCompareNumbers(t *testing.T) {
+	testCases := []struct {
+		a, b any
+		want int
+	}{
+		{int32(1), int64(1), 0},
+		{int32(1), float64(1), 0},
+		{int64(1 << 53), float64(1 << 53), 0},
+		{int64(1<<53 + 1), float64(1 << 53), 1},
+		{uint64(math.MaxUint64), int64(math.MaxInt64), 1},
+		{float64(-0.0), int32(0), 0},
+		{math.NaN(), math.Inf(-1), -1},
+		{math.NaN(), math.NaN(), 0},
+		{int32(2), float64(1.5), 1},
+		{uint8(3), int16(4), -1},
+		{math.Inf(1), int64(math.MaxInt64), 1},
+
+		// 1.5 as decimal128: coefficient 15, exponent -1.
+		{decimal(15, -1), float64(1.5), 0},
+		{decimal(15, -1), int32(2), -1},
+		// 0.1 is not exact as a double.
+		{decimal(1, -1), float64(0.1), -1},
+		{decimal(-1, 0), int32(-1), 0},
+		{decimal(1, 3), int32(1000), 0},
+		{bsonproto.Decimal128{H: 0x7c00000000000000}, math.Inf(-1), -1},
+		{bsonproto.Decimal128{H: 0x7800000000000000}, float64(math.MaxFloat64), 1},
+	}
+
+	for _, tc := range testCases {
+		mustEqual(t, Compare(tc.a, tc.b), tc.want)
+		mustEqual(t, Compare(tc.b, tc.a), -tc.want)
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	testCases := []struct {
+		a, b any
+		want int
+	}{
+		{"a", "b", -1},
+		{"ab", "a", 1},
+		{true, false, 1},
+		{time.Unix(1, 0), time.Unix(2, 0), -1},
+		{Timestamp(2), Timestamp(1), 1},
+		{ObjectID{1}, ObjectID{2}, -1},
+		{Regex{Pattern: "a", Options: "i"}, Regex{Pattern: "a"}, 1},
+
+		// binary: length, then subtype, then bytes.
+		{[]byte{9}, []byte{1, 1}, -1},
+		{bsonproto.Binary{B: []byte{1}, Subtype: 4}, []byte{2}, 1},
+		{[]byte{1}, []byte{2}, -1},
+
+		// documents: type of the field, name, value.
+		{D{{"a", int32(1)}}, D{{"a", int32(1)}}, 0},
+		{D{{"a", int32(1)}}, D{{"a", int64(1)}}, 0},
+		{D{{"a", int32(1)}}, D{{"a", "x"}}, -1},
+		{D{{"a", int32(1)}}, D{{"b", int32(1)}}, -1},
+		{D{{"a", int32(1)}}, D{{"a", int32(2)}}, -1},
+		{D{{"a", int32(1)}}, D{{"a", int32(1)}, {"b", nil}}, -1},
+		{D{{"b", int32(1)}, {"a", int32(1)}}, M{"a": int32(1), "b": int32(1)}, 1},
+		{M{"a": int32(1)}, map[string]any{"a": float64(1)}, 0},
+
+		// arrays.
+		{A{int32(1), int32(2)}, []any{int32(1), int32(3)}, -1},
+		{A{int32(1)}, A{int32(1), int32(0)}, -1},
+		{[]string{"a", "b"}, A{"a", "b"}, 0},
+
+		// reflection.
+		{struct{ A int }{1}, D{{"a", int32(1)}}, 0},
+		{map[string]string{"a": "b"}, D{{"a", "b"}}, 0},
+		{(*int)(nil), nil, 0},
+	}
+
+	for _, tc := range testCases {
+		mustEqual(t, Compare(tc.a, tc.b), tc.want)
+		mustEqual(t, Compare(tc.b, tc.a), -tc.want)
+	}
+}
+
+func TestCompareRaw(t *testing.T) {
+	doc := D{
+		{"b", int32(1)},
+		{"a", D{{"y", "str"}, {"x", A{int32(1), true}}}},
+	}
+	raw := RawObject(must(Marshal(doc)))
+
+	mustEqual(t, Compare(raw, doc), 0)
+	mustEqual(t, Compare(raw, D{{"b", int32(2)}}), -1)
+
+	arr := RawArray(must(Marshal(A{int32(1), "x"})))
+	mustEqual(t, Compare(arr, A{float64(1), "x"}), 0)
+	mustEqual(t, Compare(arr, A{int32(1)}), 1)
+}
+
+func TestCompareSort(t *testing.T) {
+	values := []any{"b", int32(3), nil, D{}, float64(1.5), MaxKey{}, "a", int64(2), MinKey{}}
+	sort.Slice(values, func(i, j int) bool {
+		return Compare(values[i], values[j]) < 0
+	})
+
+	mustDeepEqual(t, values, []any{MinKey{}, nil, float64(1.5), int64(2), int32(3), "a", "b", D{}, MaxKey{}})
+}
+
+func TestCompareInvalid(t *testing.T) {
+	mustEqual(t, Compare(make(chan int), nil), 1)
+	mustEqual(t, Compare(MaxKey{}, RawObject{1, 2}), -1)
+	mustEqual(t, Compare(RawObject{1, 2}, RawObject{}), 0)
+	mustEqual(t, Compare(RawArray{1}, make(chan int)), 0)
+	mustEqual(t, Compare(D{{"a", RawObject{1}}}, D{{"a", MaxKey{}}}), 1)
+
+	mustEqual(t, Equal(RawObject{1, 2}, RawObject{1, 2}, nil), false)
+	mustEqual(t, Hash(RawObject{1, 2}), Hash(make(chan int)))
+
+	var uve *UnsupportedValueError
+	err := checkCanonical(D{{"a", A{int32(1), make(chan int)}}}, 1)
+	mustEqual(t, errors.As(err, &uve), true)
+	mustEqual(t, uve.Path, "a.1")
+	mustFail(t, checkCanonical(M{"a": RawObject{1, 2, 3}}, 1))
+	mustOk(t, checkCanonical(D{{"a", A{int32(1), RawObject(must(Marshal(D{})))}}}, 1))
+
+	self := D{{"a", nil}}
+	self[0].Value = self
+	mustEqual(t, errors.Is(checkCanonical(self, 1), ErrMaxDepthExceeded), true)
+
+	doc := D{{"x", int32(1)}}
+	for i := 1; i < DefaultMaxDepth; i++ {
+		doc = D{{"x", doc}}
+	}
+	mustOk(t, checkCanonical(doc, 1))
+	mustEqual(t, errors.Is(checkCanonical(D{{"x", doc}}, 1), ErrMaxDepthExceeded), true)
+}
+
+// decimal returns decimal128 value of coef*10^exp.
+func decimal(coef int64, exp int) bsonproto.Decimal128 {
+	var d bsonproto.Decimal128
+	if coef < 0 {
+		d.H = 1 << 63
+		coef = -coef
+	}
+	d.L = uint64(coef)
+	d.H |= uint64(exp+6176) << 49
+	return d
+}
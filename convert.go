@@ -0,0 +1,157 @@
+package bson
+
+import "sort"
+
+// ToMDeep returns the document as M with all nested documents converted to M
+// and arrays converted to A. Values are copied, the result doesn't share memory with d.
+// If a key is duplicated, the last value wins.
+//
+// It panics if d is nested deeper than [DefaultMaxDepth], see [DeepCopy].
+func (d D) ToMDeep() M {
+	m, err := toMDeep(d, 1)
+	if err != nil {
+		panic(err)
+	}
+	return m.(M)
+}
+
+// ToDDeep returns the document as D with all nested documents converted to D
+// and arrays converted to A. Keys are sorted as in [M.AsD].
+// Values are copied, the result doesn't share memory with m.
+//
+// It panics if m is nested deeper than [DefaultMaxDepth], see [DeepCopy].
+func (m M) ToDDeep() D {
+	d, err := toDDeep(m, 1)
+	if err != nil {
+		panic(err)
+	}
+	return d.(D)
+}
+
+// ToD decodes the raw document into D preserving order of elements.
+// Nested documents are decoded as D and arrays as A, deprecated types
+// (undefined, DBPointer, JavaScript code, symbol) are kept as [RawValue],
+// encoding the result with [Marshal] gives the same bytes.
+// Documents nested deeper than [DefaultMaxDepth] return [ErrMaxDepthExceeded].
+func (r RawObject) ToD() (D, error) {
+	return rawToD(r, 1)
+}
+
+// ToA decodes the raw array into A.
+// Nested documents are decoded as D and arrays as A, see [RawObject.ToD].
+func (r RawArray) ToA() (A, error) {
+	return rawToA(r, 1)
+}
+
+// ToRaw encodes the document into RawObject.
+func ToRaw(v any) (RawObject, error) {
+	b, err := Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return RawObject(b), nil
+}
+
+// toMDeep converts v to M, depth is the nesting level of v starting from 1.
+func toMDeep(v any, depth int) (any, error) {
+	switch v := v.(type) {
+	case D:
+		if err := checkCopyDepth(depth); err != nil {
+			return nil, err
+		}
+		m := make(M, len(v))
+		for _, elem := range v {
+			val, err := toMDeep(elem.Value, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			m[elem.Key] = val
+		}
+		return m, nil
+	case M:
+		return toMapDeep(v, depth)
+	case map[string]any:
+		return toMapDeep(v, depth)
+	case A:
+		return toArrayDeep(v, depth, toMDeep)
+	case []any:
+		return toArrayDeep(v, depth, toMDeep)
+	default:
+		return deepCopyValue(v, depth)
+	}
+}
+
+// toDDeep converts v to D, depth is the nesting level of v starting from 1.
+func toDDeep(v any, depth int) (any, error) {
+	switch v := v.(type) {
+	case D:
+		if err := checkCopyDepth(depth); err != nil {
+			return nil, err
+		}
+		d := make(D, len(v))
+		for i, elem := range v {
+			val, err := toDDeep(elem.Value, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			d[i] = E{Key: elem.Key, Value: val}
+		}
+		return d, nil
+	case M:
+		return mapToDDeep(v, depth)
+	case map[string]any:
+		return mapToDDeep(v, depth)
+	case A:
+		return toArrayDeep(v, depth, toDDeep)
+	case []any:
+		return toArrayDeep(v, depth, toDDeep)
+	default:
+		return deepCopyValue(v, depth)
+	}
+}
+
+func mapToDDeep(m map[string]any, depth int) (D, error) {
+	if err := checkCopyDepth(depth); err != nil {
+		return nil, err
+	}
+	d := make(D, 0, len(m))
+	for k, v := range m {
+		val, err := toDDeep(v, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		d = append(d, E{Key: k, Value: val})
+	}
+	sort.Sort(d)
+	return d, nil
+}
+
+func toArrayDeep(a []any, depth int, conv func(any, int) (any, error)) (A, error) {
+	if err := checkCopyDepth(depth); err != nil {
+		return nil, err
+	}
+	res := make(A, len(a))
+	for i, v := range a {
+		val, err := conv(v, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = val
+	}
+	return res, nil
+}
+
+func toMapDeep(m map[string]any, depth int) (M, error) {
+	if err := checkCopyDepth(depth); err != nil {
+		return nil, err
+	}
+	res := make(M, len(m))
+	for k, v := range m {
+		val, err := toMDeep(v, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		res[k] = val
+	}
+	return res, nil
+}
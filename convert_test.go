@@ -0,0 +1,208 @@
+package bson
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cristalhq/bson/bsonproto"
+)
+
+func TestRawRoundTrip(t *testing.T) {
+	doc := D{
+		{"double", 3.14},
+		{"negzero", math.Copysign(0, -1)},
+		{"string", "hello"},
+		{"empty", ""},
+		{"doc", D{{"z", int32(1)}, {"a", int32(2)}, {"z", "dup"}}},
+		{"array", A{int32(1), "x", A{D{{"k", nil}}}}},
+		{"binary", bsonproto.Binary{B: []byte{1, 2, 3}, Subtype: bsonproto.BinaryUser}},
+		{"oid", NewObjectID()},
+		{"bool", true},
+		{"date", time.UnixMilli(1691690746123).UTC()},
+		{"null", nil},
+		{"regex", Regex{Pattern: "^a", Options: "i"}},
+		{"int32", int32(-42)},
+		{"ts", NewTimestampWithCounter(time.Unix(1691690746, 0), 7)},
+		{"int64", int64(math.MaxInt64)},
+		{"decimal", decimal(12345, -2)},
+		{"min", MinKey{}},
+		{"max", MaxKey{}},
+	}
+
+	raw, err := ToRaw(doc)
+	mustOk(t, err)
+
+	d, err := raw.ToD()
+	mustOk(t, err)
+	mustEqual(t, Equal(d, doc, &EqualOptions{StrictNumbers: true}), true)
+
+	raw2, err := ToRaw(d)
+	mustOk(t, err)
+	if !bytes.Equal(raw, raw2) {
+		t.Fatalf("\nhave %x\nwant %x", raw2, raw)
+	}
+
+	arr, err := RawArray(must(Marshal(A{int32(1), D{{"a", "b"}}}))).ToA()
+	mustOk(t, err)
+	mustDeepEqual(t, arr, A{int32(1), D{{"a", "b"}}})
+
+	_, err = RawObject{1, 2, 3}.ToD()
+	mustFail(t, err)
+}
+
+func TestRawRoundTripDeprecated(t *testing.T) {
+	body := unhex("067500" + // undefined
+		"0c7000" + "030000006e7300" + "0102030405060708090a0b0c" + // DBPointer
+		"0d6300" + "020000006100" + // JavaScript code
+		"0e7300" + "020000007300" + // symbol
+		"0f7700" + "0f000000" + "020000006100" + "0500000000") // JavaScript code with scope
+	n := len(body) + 5
+	raw := RawObject(append(append([]byte{byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24)}, body...), 0))
+
+	d, err := raw.ToD()
+	mustOk(t, err)
+	mustEqual(t, len(d), 5)
+	for _, elem := range d {
+		_, ok := elem.Value.(RawValue)
+		mustEqual(t, ok, true)
+	}
+	wantBytes(t, must(Marshal(d)), hex.EncodeToString(raw))
+
+	var ud D
+	mustOk(t, Unmarshal(raw, &ud))
+	mustDeepEqual(t, ud, d)
+
+	arr, err := RawArray(must(Marshal(A{d[3].Value, d[4].Value}))).ToA()
+	mustOk(t, err)
+	mustDeepEqual(t, arr, A{d[3].Value, d[4].Value})
+
+	mustEqual(t, Compare(d[0].Value, nil), -1)
+	mustEqual(t, Compare(d[0].Value, MinKey{}), 1)
+	mustEqual(t, Compare(d[3].Value, "s"), 0)
+	mustEqual(t, Compare(d[1].Value, Regex{}), 1)
+	mustEqual(t, Compare(d[2].Value, d[1].Value), 1)
+	mustEqual(t, Compare(d[4].Value, d[2].Value), 1)
+	mustEqual(t, Compare(d[4].Value, MaxKey{}), -1)
+	mustEqual(t, Equal(d, must(raw.ToD()), nil), true)
+	mustEqual(t, Hash(d), Hash(raw))
+}
+
+func TestRawMaxDepth(t *testing.T) {
+	nested := func(depth int) D {
+		doc := D{{"x", A{int32(1)}}}
+		for i := 2; i < depth; i++ {
+			doc = D{{"x", doc}}
+		}
+		return doc
+	}
+
+	raw := RawObject(must(Marshal(nested(DefaultMaxDepth))))
+	_, err := raw.ToD()
+	mustOk(t, err)
+	_ = DeepCopy(nested(DefaultMaxDepth))
+
+	// Marshal doesn't allow deeper documents, wrap the raw one.
+	deep := RawObject(must(Marshal(D{{"x", raw}})))
+	_, err = deep.ToD()
+	mustEqual(t, errors.Is(err, ErrMaxDepthExceeded), true)
+	_, err = RawArray(must(Marshal(A{raw}))).ToA()
+	mustEqual(t, errors.Is(err, ErrMaxDepthExceeded), true)
+
+	self := D{{"a", nil}}
+	self[0].Value = self
+	_, err = DeepCopyErr(self)
+	mustEqual(t, errors.Is(err, ErrMaxDepthExceeded), true)
+	_, err = DeepCopyErr(nested(DefaultMaxDepth + 1))
+	mustEqual(t, errors.Is(err, ErrMaxDepthExceeded), true)
+
+	selfM := M{}
+	selfM["self"] = selfM
+	_, err = DeepCopyErr(selfM)
+	mustEqual(t, errors.Is(err, ErrMaxDepthExceeded), true)
+
+	mustPanicDepth(t, func() { DeepCopy(self) })
+	mustPanicDepth(t, func() { self.ToMDeep() })
+	mustPanicDepth(t, func() { selfM.ToDDeep() })
+	mustPanicDepth(t, func() { nested(DefaultMaxDepth + 1).ToMDeep() })
+	_ = nested(DefaultMaxDepth).ToMDeep()
+	_ = M{"x": nested(DefaultMaxDepth - 1)}.ToDDeep()
+}
+
+func mustPanicDepth(t testing.TB, fn func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		err, _ := recover().(error)
+		mustEqual(t, errors.Is(err, ErrMaxDepthExceeded), true)
+	}()
+	fn()
+}
+
+func TestDeepConversions(t *testing.T) {
+	d := D{
+		{"b", D{{"y", int32(1)}, {"x", A{D{{"k", "v"}}, M{"n": nil}}}}},
+		{"a", []byte{1}},
+	}
+
+	m := d.ToMDeep()
+	mustDeepEqual(t, m, M{
+		"b": M{"y": int32(1), "x": A{M{"k": "v"}, M{"n": nil}}},
+		"a": []byte{1},
+	})
+
+	d2 := m.ToDDeep()
+	mustDeepEqual(t, d2, D{
+		{"a", []byte{1}},
+		{"b", D{{"x", A{D{{"k", "v"}}, D{{"n", nil}}}}, {"y", int32(1)}}},
+	})
+
+	m["a"].([]byte)[0] = 42
+	mustDeepEqual(t, d[1].Value, []byte{1})
+	mustDeepEqual(t, d2[0].Value, []byte{1})
+}
+
+func TestDeepCopy(t *testing.T) {
+	d := D{
+		{"a", A{int32(1), D{{"b", []byte{1}}}}},
+		{"m", map[string]any{"x": []any{"y"}}},
+		{"bin", bsonproto.Binary{B: []byte{2}}},
+		{"raw", RawObject(must(Marshal(D{{"k", "v"}})))},
+	}
+
+	cp := DeepCopy(d)
+	mustDeepEqual(t, cp, d)
+
+	cp[0].Value.(A)[1].(D)[0].Value.([]byte)[0] = 42
+	cp[1].Value.(map[string]any)["x"].([]any)[0] = "z"
+	cp[2].Value.(bsonproto.Binary).B[0] = 42
+	cp[3].Value.(RawObject)[4] = 42
+
+	mustDeepEqual(t, d, D{
+		{"a", A{int32(1), D{{"b", []byte{1}}}}},
+		{"m", map[string]any{"x": []any{"y"}}},
+		{"bin", bsonproto.Binary{B: []byte{2}}},
+		{"raw", RawObject(must(Marshal(D{{"k", "v"}})))},
+	})
+
+	type node struct {
+		Name  string
+		Tags  []string
+		Attrs map[string]any
+		Next  *node
+	}
+	n := &node{Name: "a", Tags: []string{"x"}, Attrs: map[string]any{"d": D{{"k", int32(1)}}}}
+	n.Next = n
+
+	ncp := DeepCopy(n)
+	mustEqual(t, ncp.Next, ncp)
+	ncp.Tags[0] = "y"
+	ncp.Attrs["d"].(D)[0].Value = "changed"
+	mustEqual(t, n.Tags[0], "x")
+	mustDeepEqual(t, n.Attrs["d"], D{{"k", int32(1)}})
+
+	mustDeepEqual(t, DeepCopy[any](nil), nil)
+}
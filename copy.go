@@ -0,0 +1,267 @@
+package bson
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/cristalhq/bson/bsonproto"
+)
+
+// DeepCopy returns a copy of v which doesn't share memory with v.
+// Nested documents, arrays, maps, slices, pointers and binary data are cloned,
+// unexported struct fields are copied shallowly.
+//
+// It panics if v is nested deeper than [DefaultMaxDepth], like a document
+// which contains itself, use [DeepCopyErr] to get an error instead.
+func DeepCopy[T any](v T) T {
+	res, err := DeepCopyErr(v)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// DeepCopyErr is like [DeepCopy] but returns [ErrMaxDepthExceeded]
+// if v is nested deeper than [DefaultMaxDepth].
+func DeepCopyErr[T any](v T) (T, error) {
+	cp, err := deepCopyValue(v, 1)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	res, _ := cp.(T)
+	return res, nil
+}
+
+// deepCopyValue returns a copy of v which doesn't share memory with v,
+// depth is the nesting level of v starting from 1.
+func deepCopyValue(v any, depth int) (any, error) {
+	switch v.(type) {
+	case D, M, map[string]any, A, []any:
+		if err := checkCopyDepth(depth); err != nil {
+			return nil, err
+		}
+	}
+
+	switch v := v.(type) {
+	case nil, string, bool, int32, int64, float64, ObjectID, Timestamp, Regex, MinKey, MaxKey:
+		return v, nil
+	case D:
+		if v == nil {
+			return v, nil
+		}
+		d := make(D, len(v))
+		for i, elem := range v {
+			val, err := deepCopyValue(elem.Value, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			d[i] = E{Key: elem.Key, Value: val}
+		}
+		return d, nil
+	case M:
+		if v == nil {
+			return v, nil
+		}
+		m := make(M, len(v))
+		for k, val := range v {
+			cp, err := deepCopyValue(val, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = cp
+		}
+		return m, nil
+	case map[string]any:
+		if v == nil {
+			return v, nil
+		}
+		m := make(map[string]any, len(v))
+		for k, val := range v {
+			cp, err := deepCopyValue(val, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = cp
+		}
+		return m, nil
+	case A:
+		if v == nil {
+			return v, nil
+		}
+		a := make(A, len(v))
+		for i, val := range v {
+			cp, err := deepCopyValue(val, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			a[i] = cp
+		}
+		return a, nil
+	case []any:
+		if v == nil {
+			return v, nil
+		}
+		a := make([]any, len(v))
+		for i, val := range v {
+			cp, err := deepCopyValue(val, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			a[i] = cp
+		}
+		return a, nil
+	case []byte:
+		if v == nil {
+			return v, nil
+		}
+		return append([]byte{}, v...), nil
+	case RawObject:
+		if v == nil {
+			return v, nil
+		}
+		return append(RawObject{}, v...), nil
+	case RawArray:
+		if v == nil {
+			return v, nil
+		}
+		return append(RawArray{}, v...), nil
+	case bsonproto.Binary:
+		if v.B != nil {
+			v.B = append([]byte{}, v.B...)
+		}
+		return v, nil
+	}
+
+	cp, err := deepCopyReflect(reflect.ValueOf(v), map[uintptr]reflect.Value{}, depth)
+	if err != nil {
+		return nil, err
+	}
+	return cp.Interface(), nil
+}
+
+// deepCopyReflect copies values of arbitrary types,
+// seen keeps copied pointers to preserve aliasing and to stop on cycles.
+func deepCopyReflect(v reflect.Value, seen map[uintptr]reflect.Value, depth int) (reflect.Value, error) {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		if err := checkCopyDepth(depth); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v, nil
+		}
+		if cp, ok := seen[v.Pointer()]; ok {
+			return cp, nil
+		}
+		cp := reflect.New(v.Type().Elem())
+		seen[v.Pointer()] = cp
+		elem, err := deepCopyReflect(v.Elem(), seen, depth)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		cp.Elem().Set(elem)
+		return cp, nil
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, nil
+		}
+		elem, err := deepCopyElem(v.Elem(), seen, depth)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(elem)
+		return cp, nil
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v, nil
+		}
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			elem, err := deepCopyElem(v.Index(i), seen, depth+1)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			cp.Index(i).Set(elem)
+		}
+		return cp, nil
+
+	case reflect.Array:
+		cp := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			elem, err := deepCopyElem(v.Index(i), seen, depth+1)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			cp.Index(i).Set(elem)
+		}
+		return cp, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v, nil
+		}
+		cp := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			elem, err := deepCopyElem(iter.Value(), seen, depth+1)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			cp.SetMapIndex(iter.Key(), elem)
+		}
+		return cp, nil
+
+	case reflect.Struct:
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if !cp.Field(i).CanSet() {
+				continue
+			}
+			elem, err := deepCopyElem(v.Field(i), seen, depth+1)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			cp.Field(i).Set(elem)
+		}
+		return cp, nil
+
+	default:
+		return v, nil
+	}
+}
+
+// deepCopyElem copies a nested value, known BSON types take the fast path.
+func deepCopyElem(v reflect.Value, seen map[uintptr]reflect.Value, depth int) (reflect.Value, error) {
+	if v.Kind() == reflect.Interface {
+		return deepCopyReflect(v, seen, depth)
+	}
+	if !v.CanInterface() {
+		return v, nil
+	}
+
+	switch v.Interface().(type) {
+	case D, M, A, []any, map[string]any, []byte, RawObject, RawArray, bsonproto.Binary:
+		cp, err := deepCopyValue(v.Interface(), depth)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(cp), nil
+	}
+	return deepCopyReflect(v, seen, depth)
+}
+
+func checkCopyDepth(depth int) error {
+	if depth > DefaultMaxDepth {
+		return fmt.Errorf("bson: deep copy: %w: max depth is %d", ErrMaxDepthExceeded, DefaultMaxDepth)
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package bson
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/cristalhq/bson/bsonproto"
+)
+
+// Limits of IEEE 754-2008 decimal128.
+const (
+	decimalDigits = 34
+	decimalMinExp = -6176
+	decimalMaxExp = 6111
+)
+
+var (
+	decimalNaN         = bsonproto.Decimal128{H: 0x7c00 << 48}
+	decimalInf         = bsonproto.Decimal128{H: 0x7800 << 48}
+	maxDecimal         = new(big.Int).Exp(big.NewInt(10), big.NewInt(decimalDigits), nil)
+	bigTen             = big.NewInt(10)
+	errDecimalOverflow = errors.New("decimal128 overflow")
+)
+
+// decimalArith returns a+b or a*b as Decimal128, a and b are canonical numbers.
+// Results are rounded half to even to 34 digits as MongoDB does.
+func decimalArith(op string, a, b any) (bsonproto.Decimal128, error) {
+	xc, xe, xok := decimalParts(a)
+	yc, ye, yok := decimalParts(b)
+	if !xok || !yok {
+		// NaN or Infinity, the result is the same as for floats.
+		fx, _ := asNumber(a)
+		fy, _ := asNumber(b)
+		return decimalSpecial(op, fx, fy), nil
+	}
+
+	res := new(big.Int)
+	exp := xe + ye
+	if op == "$inc" {
+		exp = xe
+		if ye < xe {
+			exp = ye
+		}
+		res.Add(scaleDecimal(xc, xe-exp), scaleDecimal(yc, ye-exp))
+	} else {
+		res.Mul(xc, yc)
+	}
+	return newDecimal(res, exp)
+}
+
+// decimalParts returns the coefficient and the exponent of a canonical number,
+// reports false for NaN and Infinity.
+func decimalParts(v any) (*big.Int, int, bool) {
+	switch v := v.(type) {
+	case int64:
+		return big.NewInt(v), 0, true
+	case uint64:
+		return new(big.Int).SetUint64(v), 0, true
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, 0, false
+		}
+		// doubles are converted with 15 significant digits as MongoDB does.
+		s := strconv.FormatFloat(v, 'e', 14, 64)
+		mant, e, _ := strings.Cut(s, "e")
+		exp, _ := strconv.Atoi(e)
+		coef, _ := new(big.Int).SetString(strings.Replace(mant, ".", "", 1), 10)
+		return coef, exp - 14, true
+	case bsonproto.Decimal128:
+		if (v.H>>58)&0x1e == 0x1e {
+			return nil, 0, false
+		}
+		coef := new(big.Int)
+		var exp int
+		if (v.H>>61)&3 == 3 {
+			// coefficient is bigger than 10^34, non-canonical, must be treated as zero.
+			exp = int((v.H >> 47) & 0x3fff)
+		} else {
+			exp = int((v.H >> 49) & 0x3fff)
+			coef.SetUint64(v.H & (1<<49 - 1))
+			coef.Lsh(coef, 64)
+			coef.Or(coef, new(big.Int).SetUint64(v.L))
+		}
+		if v.H>>63 == 1 {
+			coef.Neg(coef)
+		}
+		return coef, exp + decimalMinExp, true
+	default:
+		panic("unreachable")
+	}
+}
+
+// decimalSpecial returns the result of an operation with NaN or Infinity.
+func decimalSpecial(op string, x, y float64) bsonproto.Decimal128 {
+	res := x + y
+	if op == "$mul" {
+		res = x * y
+	}
+	switch {
+	case math.IsNaN(res):
+		return decimalNaN
+	case math.IsInf(res, -1):
+		return bsonproto.Decimal128{H: decimalInf.H | 1<<63}
+	default:
+		return decimalInf
+	}
+}
+
+// scaleDecimal returns coef*10^n.
+func scaleDecimal(coef *big.Int, n int) *big.Int {
+	scale := new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+	return scale.Mul(scale, coef)
+}
+
+// newDecimal returns coef*10^exp rounded to Decimal128.
+func newDecimal(coef *big.Int, exp int) (bsonproto.Decimal128, error) {
+	neg := coef.Sign() < 0
+	coef = new(big.Int).Abs(coef)
+
+	for coef.Cmp(maxDecimal) >= 0 || exp < decimalMinExp {
+		coef = roundTen(coef)
+		exp++
+	}
+	for exp > decimalMaxExp && coef.Sign() != 0 {
+		next := new(big.Int).Mul(coef, bigTen)
+		if next.Cmp(maxDecimal) >= 0 {
+			return bsonproto.Decimal128{}, errDecimalOverflow
+		}
+		coef = next
+		exp--
+	}
+	if exp > decimalMaxExp {
+		exp = decimalMaxExp
+	}
+
+	var res bsonproto.Decimal128
+	res.L = coef.Uint64()
+	res.H = new(big.Int).Rsh(coef, 64).Uint64() | uint64(exp-decimalMinExp)<<49
+	if neg {
+		res.H |= 1 << 63
+	}
+	return res, nil
+}
+
+// roundTen returns coef/10 rounded half to even.
+func roundTen(coef *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(coef, bigTen, new(big.Int))
+	if d := r.Int64(); d > 5 || (d == 5 && q.Bit(0) == 1) {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
+
+// formatDecimal returns the string representation of d as defined by
+// the Extended JSON and decimal128 specifications.
+func formatDecimal(d bsonproto.Decimal128) string {
+	neg := d.H>>63 == 1
+	sign := ""
+	if neg {
+		sign = "-"
+	}
+
+	coef, exp, ok := decimalParts(d)
+	switch {
+	case ok:
+	case (d.H>>58)&0x1f == 0x1f:
+		return "NaN"
+	default:
+		return sign + "Infinity"
+	}
+
+	digits := new(big.Int).Abs(coef).String()
+	adjusted := exp + len(digits) - 1
+
+	if exp > 0 || adjusted < -6 {
+		s := digits[:1]
+		if len(digits) > 1 {
+			s += "." + digits[1:]
+		}
+		if adjusted >= 0 {
+			return sign + s + "E+" + strconv.Itoa(adjusted)
+		}
+		return sign + s + "E" + strconv.Itoa(adjusted)
+	}
+
+	if exp == 0 {
+		return sign + digits
+	}
+	if n := -exp - len(digits); n >= 0 {
+		digits = strings.Repeat("0", n+1) + digits
+	}
+	point := len(digits) + exp
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// parseDecimal parses the string representation of Decimal128,
+// values which can't be represented exactly return an error.
+func parseDecimal(s string) (bsonproto.Decimal128, error) {
+	str := s
+	neg := strings.HasPrefix(str, "-")
+	if neg || strings.HasPrefix(str, "+") {
+		str = str[1:]
+	}
+
+	var res bsonproto.Decimal128
+	switch strings.ToLower(str) {
+	case "nan":
+		return decimalNaN, nil
+	case "inf", "infinity":
+		res = decimalInf
+	default:
+		coef, exp, ok := parseDecimalParts(str)
+		if !ok {
+			return bsonproto.Decimal128{}, fmt.Errorf("invalid decimal128 %q", s)
+		}
+		switch {
+		case coef.Sign() == 0 && exp < decimalMinExp:
+			exp = decimalMinExp
+		case coef.Sign() == 0 && exp > decimalMaxExp:
+			exp = decimalMaxExp
+		case coef.Sign() != 0 && (exp < decimalMinExp-len(str) || exp > decimalMaxExp+decimalDigits):
+			return bsonproto.Decimal128{}, fmt.Errorf("decimal128 %q is out of range", s)
+		}
+		var err error
+		if res, err = newDecimal(coef, exp); err != nil {
+			return bsonproto.Decimal128{}, err
+		}
+		if c, e, _ := decimalParts(res); !equalScaled(c, e, coef, exp) {
+			return bsonproto.Decimal128{}, fmt.Errorf("decimal128 %q is inexact", s)
+		}
+	}
+	if neg {
+		res.H |= 1 << 63
+	}
+	return res, nil
+}
+
+// parseDecimalParts parses unsigned digits with an optional fraction and exponent.
+func parseDecimalParts(s string) (*big.Int, int, bool) {
+	mant, e, hasExp := strings.Cut(strings.ToLower(s), "e")
+	exp := 0
+	if hasExp {
+		n, err := strconv.Atoi(e)
+		if err != nil || len(e) > 6 {
+			return nil, 0, false
+		}
+		exp = n
+	}
+
+	intPart, frac, _ := strings.Cut(mant, ".")
+	digits := intPart + frac
+	if digits == "" || strings.TrimLeft(digits, "0123456789") != "" {
+		return nil, 0, false
+	}
+	coef, _ := new(big.Int).SetString(digits, 10)
+	return coef, exp - len(frac), true
+}
+
+// equalScaled reports whether x*10^xe equals y*10^ye.
+func equalScaled(x *big.Int, xe int, y *big.Int, ye int) bool {
+	if x.Sign() == 0 || y.Sign() == 0 {
+		return x.Sign() == y.Sign()
+	}
+	if xe > ye {
+		return scaleDecimal(x, xe-ye).Cmp(y) == 0
+	}
+	return scaleDecimal(y, ye-xe).Cmp(x) == 0
+}
@@ -7,14 +7,49 @@ import (
 	"io"
 	"math"
 	"reflect"
+	"time"
+
+	"github.com/cristalhq/bson/bsonproto"
 )
 
 // Decoder reads and decodes BSON values from an input stream.
 type Decoder struct {
-	r    io.Reader
-	data []byte
+	r        io.Reader
+	data     []byte
+	buf      []byte
+	off      int // offset of the next document in the input.
+	maxDepth int
+	maxSize  int
+	utf8     UTF8Mode
+	dups     DuplicateKeyPolicy
+	docType  DocumentType
+	arrType  ArrayType
+
+	disallowUnknown bool
+}
+
+// decodeState holds the options of a single Decode call.
+type decodeState struct {
+	utf8     UTF8Mode
+	dups     DuplicateKeyPolicy
+	docType  DocumentType
+	arrType  ArrayType
+	maxDepth int
+	depth    int // nesting level of the current document or array.
+
+	disallowUnknown bool
+	path            []string // path of the current element when unknown fields are tracked.
+	unknown         []string // paths of unknown fields.
 }
 
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		r: r,
+	}
+}
+
+// NewDecodeBytes returns a new decoder that reads from buf.
 func NewDecodeBytes(buf []byte) *Decoder {
 	d := &Decoder{
 		data: buf,
@@ -22,13 +57,160 @@ func NewDecodeBytes(buf []byte) *Decoder {
 	return d
 }
 
+// Decode reads the next BSON document from its input
+// and stores it in the value pointed to by v.
+//
+// At the end of the input Decode returns [io.EOF].
 func (dec *Decoder) Decode(v any) error {
-	if len(dec.data) < 4 {
-		return errors.New("not enough data") // TODO(cristaloleg): static error?
+	off := dec.off
+	data, err := dec.next()
+	if err != nil {
+		return withOffset(err, off)
+	}
+	dec.off += len(data)
+
+	ds := decodeState{
+		utf8:            dec.utf8,
+		maxDepth:        dec.maxDepth,
+		dups:            dec.dups,
+		docType:         dec.docType,
+		arrType:         dec.arrType,
+		disallowUnknown: dec.disallowUnknown,
+	}
+	if err := ds.decodeDocument(data, v); err != nil {
+		return withOffset(err, off)
 	}
+	if len(ds.unknown) > 0 {
+		return &UnknownFieldsError{Fields: ds.unknown}
+	}
+	return nil
+}
+
+// withOffset moves the offset of a [DecodeError] by the offset of the document in the input.
+func withOffset(err error, off int) error {
+	if de, ok := err.(*DecodeError); ok {
+		de.Offset += off
+	}
+	return err
+}
 
+// next returns the next document from the input.
+func (dec *Decoder) next() ([]byte, error) {
+	if dec.r != nil {
+		return dec.readNext()
+	}
+
+	if len(dec.data) == 0 {
+		return nil, io.EOF
+	}
+	if len(dec.data) < 5 {
+		return nil, &DecodeError{Err: fmt.Errorf("%w: have %d bytes", ErrShortInput, len(dec.data))}
+	}
+
+	size, _ := readInt32(dec.data)
+	switch {
+	case size < 5:
+		return nil, &DecodeError{Err: fmt.Errorf("%w: invalid document size %d", ErrInvalidInput, size)}
+	case size > len(dec.data):
+		return nil, &DecodeError{Err: fmt.Errorf("%w: document size %d, have %d bytes", ErrShortInput, size, len(dec.data))}
+	}
+	if err := dec.checkSize(size); err != nil {
+		return nil, err
+	}
+	data := dec.data[:size]
+	dec.data = dec.data[size:]
+	return data, nil
+}
+
+func (dec *Decoder) readNext() ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(dec.r, size[:]); err != nil {
+		return nil, err
+	}
+
+	n, _ := readInt32(size[:])
+	if n < 5 {
+		return nil, &DecodeError{Err: fmt.Errorf("%w: invalid document size %d", ErrInvalidInput, n)}
+	}
+	if err := dec.checkSize(n); err != nil {
+		return nil, err
+	}
+
+	// grow the buffer while reading, a corrupted size must not allocate memory upfront.
+	dec.buf = append(dec.buf[:0], size[:]...)
+	for len(dec.buf) < n {
+		start := len(dec.buf)
+		chunk := n - start
+		if chunk > readChunkSize {
+			chunk = readChunkSize
+		}
+		dec.buf = append(dec.buf, make([]byte, chunk)...)
+
+		if _, err := io.ReadFull(dec.r, dec.buf[start:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	return dec.buf, nil
+}
+
+// readChunkSize is the max number of bytes read from io.Reader at once.
+const readChunkSize = 64 << 10
+
+// DocumentIter iterates over documents in a [Decoder].
+type DocumentIter[T any] struct {
+	dec *Decoder
+	val T
+	err error
+}
+
+// DecodeAll returns an iterator that decodes every document of dec into T.
+//
+// Example:
+//
+//	it := bson.DecodeAll[bson.D](dec)
+//	for it.Next() {
+//		doc := it.Value()
+//	}
+//	if err := it.Err(); err != nil {
+//		// handle error
+//	}
+func DecodeAll[T any](dec *Decoder) *DocumentIter[T] {
+	return &DocumentIter[T]{dec: dec}
+}
+
+// Next decodes the next document, it returns false at the end of the input or on error.
+func (it *DocumentIter[T]) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	var val T
+	if err := it.dec.Decode(&val); err != nil {
+		if err != io.EOF {
+			it.err = err
+		}
+		return false
+	}
+	it.val = val
+	return true
+}
+
+// Value returns the last decoded document.
+func (it *DocumentIter[T]) Value() T {
+	return it.val
+}
+
+// Err returns the first error occurred during decoding.
+func (it *DocumentIter[T]) Err() error {
+	return it.err
+}
+
+func (ds *decodeState) decodeDocument(data []byte, v any) error {
 	if d, ok := v.(*D); ok {
-		return readD(dec.data, d)
+		return ds.readD(data, d)
 	}
 
 	rv := reflect.ValueOf(v)
@@ -40,315 +222,304 @@ func (dec *Decoder) Decode(v any) error {
 	}
 
 	switch rv := rv.Elem(); rv.Kind() {
+	case reflect.Interface:
+		val, err := ds.decodeAnyDocument(data)
+		if err != nil {
+			return err
+		}
+		if !reflect.TypeOf(val).AssignableTo(rv.Type()) {
+			return fmt.Errorf("%w: cannot assign %T to %v", ErrTypeMismatch, val, rv.Type())
+		}
+		rv.Set(reflect.ValueOf(val))
+		return nil
 	case reflect.Struct:
-		return decodeStruct(dec.data, rv)
+		return ds.decodeStruct(data, rv)
 	case reflect.Map:
-		return decodeMap(dec.data, rv)
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		return ds.decodeMap(data, rv)
 	default:
 		return errors.New("unmarshal unsupported: " + rv.Type().String())
 	}
 }
 
-func readD(data []byte, d *D) error {
+func (ds *decodeState) readD(data []byte, d *D) error {
+	if err := ds.enter(); err != nil {
+		return err
+	}
+	defer ds.leave()
+
 	iter, err := newReader(data)
 	if err != nil {
 		return err
 	}
 
+	start, seen := len(*d), ds.newSeen()
 	for iter.Next() {
 		typ, name, element := iter.Peek()
 
-		key := trimlast(name)
-
-		switch typ {
-		case TypeDouble:
-			val := uint64(element[0]) |
-				uint64(element[1])<<8 |
-				uint64(element[2])<<16 |
-				uint64(element[3])<<24 |
-				uint64(element[4])<<32 |
-				uint64(element[5])<<40 |
-				uint64(element[6])<<48 |
-				uint64(element[7])<<56
-			*d = append(*d, e{K: key, V: math.Float64frombits(val)})
-
-		case TypeString:
-			*d = append(*d, e{K: key, V: trimlast(element)})
-
-		case TypeDocument:
-			m := make(map[string]any)
-			vv := reflect.ValueOf(m)
-			if err := decodeMap(element, vv); err != nil {
-				return err
-			}
-			*d = append(*d, e{K: key, V: m})
+		key, err := ds.key(name)
+		if err != nil {
+			return iter.wrapErr(err)
+		}
+		skip, err := ds.dup(seen, key)
+		switch {
+		case err != nil:
+			return iter.wrapErr(err)
+		case skip:
+			continue
+		}
 
-		case TypeArray:
-			s := make([]any, 0)
-			if err := decodeSlice(element, &s); err != nil {
-				return err
-			}
-			*d = append(*d, e{K: key, V: s})
-
-		case TypeObjectID:
-			var oid ObjectID
-			copy(oid[:], element)
-			*d = append(*d, e{K: key, V: nil})
-
-		case TypeBool:
-			*d = append(*d, e{K: key, V: element[0] == 1})
-
-		case TypeInt32:
-			element := int32(element[0]) |
-				int32(element[1])<<8 |
-				int32(element[2])<<16 |
-				int32(element[3])<<24
-			*d = append(*d, e{K: key, V: element})
-
-		case TypeTimestamp:
-			ts := Timestamp(element[0]) |
-				Timestamp(element[1])<<8 |
-				Timestamp(element[2])<<16 |
-				Timestamp(element[3])<<24 |
-				Timestamp(element[4])<<32 |
-				Timestamp(element[5])<<40 |
-				Timestamp(element[6])<<48 |
-				Timestamp(element[7])<<56
-			*d = append(*d, e{K: key, V: ts})
-
-		case TypeInt64:
-			element := int64(element[0]) |
-				int64(element[1])<<8 |
-				int64(element[2])<<16 |
-				int64(element[3])<<24 |
-				int64(element[4])<<32 |
-				int64(element[5])<<40 |
-				int64(element[6])<<48 |
-				int64(element[7])<<56
-			*d = append(*d, e{K: key, V: element})
-
-		case TypeBinary,
-			TypeUndefined,
-			TypeDateTime,
-			TypeRegex,
-			TypeDBPointer,
-			TypeCodeWithScope,
-			TypeSymbol,
-			TypeJavaScriptScope,
-			TypeDecimal,
-			TypeMinKey,
-			TypeMaxKey:
-			return fmt.Errorf("unsupported type %x", typ)
-
-		default:
-			return fmt.Errorf("unknown element type %x", typ)
+		val, err := ds.decodeValue(typ, element)
+		if err != nil {
+			return iter.wrapErr(err)
+		}
+		if n := seen[key]; n > 1 {
+			i := start + (*d)[start:].index(key)
+			(*d)[i].Value = ds.merge((*d)[i].Value, val, n)
+			continue
 		}
+		*d = append(*d, E{Key: key, Value: val})
 	}
 	return iter.Err()
 }
 
-func decodeStruct(data []byte, v reflect.Value) error {
+func (ds *decodeState) decodeStruct(data []byte, v reflect.Value) error {
+	if err := ds.enter(); err != nil {
+		return err
+	}
+	defer ds.leave()
+
 	iter, err := newReader(data)
 	if err != nil {
 		return err
 	}
 
+	info, err := getStruct(v)
+	if err != nil {
+		return err
+	}
+	seen := ds.newSeen()
 	for iter.Next() {
 		typ, name, element := iter.Peek()
 
-		v := v.FieldByName(trimlast(name))
-		if !v.IsValid() {
+		key, err := ds.key(name)
+		if err != nil {
+			return iter.wrapErr(err)
+		}
+		var field reflect.Value
+		if f, ok := info.field(key); ok {
+			field = v.Field(f.Num)
+		} else {
+			field = info.fieldByName(v, key)
+		}
+		if !field.IsValid() || !field.CanSet() {
+			ds.unknownField(info, v, typ, key, element)
 			continue
 		}
 
-		switch typ {
-		case TypeDouble:
-			val := uint64(element[0]) |
-				uint64(element[1])<<8 |
-				uint64(element[2])<<16 |
-				uint64(element[3])<<24 |
-				uint64(element[4])<<32 |
-				uint64(element[5])<<40 |
-				uint64(element[6])<<48 |
-				uint64(element[7])<<56
-			v.SetFloat(math.Float64frombits(val))
-
-		case TypeString:
-			v.SetString(trimlast(element))
-
-		case TypeDocument:
-			m := make(map[string]any)
-			vv := reflect.ValueOf(m)
-			if err := decodeMap(element, vv); err != nil {
-				return err
-			}
-			v.Set(vv)
+		skip, err := ds.dup(seen, key)
+		switch {
+		case err != nil:
+			return iter.wrapErr(err)
+		case skip:
+			continue
+		}
 
-		case TypeArray:
-			s := make([]any, 0)
-			if err := decodeSlice(element, &s); err != nil {
-				return err
+		if n := seen[key]; n > 1 {
+			if ds.dups == DuplicateCollect {
+				if err := ds.collectInto(typ, element, field, n); err != nil {
+					return iter.wrapErr(err)
+				}
+				continue
 			}
-			vv := reflect.ValueOf(s)
-			v.Set(vv)
-
-		case TypeObjectID:
-			var oid ObjectID
-			copy(oid[:], element)
-			v.Set(reflect.ValueOf(oid))
-
-		case TypeBool:
-			v.SetBool(element[0] == 1)
-
-		case TypeInt32:
-			element := int64(element[0]) |
-				int64(element[1])<<8 |
-				int64(element[2])<<16 |
-				int64(element[3])<<24
-			v.SetInt(element)
-
-		case TypeTimestamp:
-			ts := Timestamp(element[0]) |
-				Timestamp(element[1])<<8 |
-				Timestamp(element[2])<<16 |
-				Timestamp(element[3])<<24 |
-				Timestamp(element[4])<<32 |
-				Timestamp(element[5])<<40 |
-				Timestamp(element[6])<<48 |
-				Timestamp(element[7])<<56
-			v.Set(reflect.ValueOf(ts))
-
-		case TypeInt64:
-			element := int64(element[0]) |
-				int64(element[1])<<8 |
-				int64(element[2])<<16 |
-				int64(element[3])<<24 |
-				int64(element[4])<<32 |
-				int64(element[5])<<40 | int64(element[6])<<48 | int64(element[7])<<56
-			v.SetInt(element)
-
-		case TypeBinary,
-			TypeUndefined,
-			TypeDateTime,
-			TypeNull,
-			TypeRegex,
-			TypeDBPointer,
-			TypeCodeWithScope,
-			TypeSymbol,
-			TypeJavaScriptScope,
-			TypeDecimal,
-			TypeMinKey,
-			TypeMaxKey:
-			return fmt.Errorf("unsupported type %x", typ)
-		default:
-			return fmt.Errorf("unknown element type %x", typ)
-
+			// the last value replaces the previous one, nested structs and maps are not merged.
+			field.Set(reflect.Zero(field.Type()))
+		}
+		ds.push(name)
+		err = ds.decodeInto(typ, element, field)
+		ds.pop()
+		if err != nil {
+			return iter.wrapErr(err)
 		}
 	}
 	return iter.Err()
 }
 
-func decodeMap(data []byte, v reflect.Value) error {
+func (ds *decodeState) decodeMap(data []byte, v reflect.Value) error {
+	if err := ds.enter(); err != nil {
+		return err
+	}
+	defer ds.leave()
+
 	iter, err := newReader(data)
 	if err != nil {
 		return err
 	}
 
+	keyType, elemType := v.Type().Key(), v.Type().Elem()
+	if keyType.Kind() != reflect.String {
+		return errors.New("unmarshal unsupported map key: " + keyType.String())
+	}
+
+	seen := ds.newSeen()
 	for iter.Next() {
 		typ, name, element := iter.Peek()
 
-		key := reflect.ValueOf(trimlast(name))
-
-		switch typ {
-		case TypeDouble:
-			val := uint64(element[0]) |
-				uint64(element[1])<<8 |
-				uint64(element[2])<<16 |
-				uint64(element[3])<<24 |
-				uint64(element[4])<<32 |
-				uint64(element[5])<<40 |
-				uint64(element[6])<<48 |
-				uint64(element[7])<<56
-			v.SetMapIndex(key, reflect.ValueOf(math.Float64frombits(val)))
-
-		case TypeString:
-			vv := reflect.ValueOf(trimlast(element))
-			v.SetMapIndex(key, vv)
-
-		case TypeDocument:
-			m := make(map[string]any)
-			vv := reflect.ValueOf(m)
-			if err := decodeMap(element, vv); err != nil {
-				return err
+		k, err := ds.key(name)
+		if err != nil {
+			return iter.wrapErr(err)
+		}
+		key := reflect.ValueOf(k).Convert(keyType)
+
+		skip, err := ds.dup(seen, k)
+		switch {
+		case err != nil:
+			return iter.wrapErr(err)
+		case skip:
+			continue
+		}
+
+		if n := seen[k]; n > 1 && ds.dups == DuplicateCollect {
+			elem := reflect.New(elemType).Elem()
+			elem.Set(v.MapIndex(key))
+			if err := ds.collectInto(typ, element, elem, n); err != nil {
+				return iter.wrapErr(err)
 			}
-			v.SetMapIndex(key, vv)
+			v.SetMapIndex(key, elem)
+			continue
+		}
 
-		case TypeArray:
-			s := make([]any, 0)
-			if err := decodeSlice(element, &s); err != nil {
-				return err
+		if elemType.Kind() != reflect.Interface {
+			elem := reflect.New(elemType).Elem()
+			ds.push(name)
+			err := ds.decodeInto(typ, element, elem)
+			ds.pop()
+			if err != nil {
+				return iter.wrapErr(err)
 			}
-			vv := reflect.ValueOf(s)
-			v.SetMapIndex(key, vv)
-
-		case TypeObjectID:
-			var oid ObjectID
-			copy(oid[:], element)
-			v.SetMapIndex(key, reflect.ValueOf(oid))
-
-		case TypeBool:
-			v.SetMapIndex(key, reflect.ValueOf(element[0] == 1))
-
-		case TypeInt32:
-			element := int32(element[0]) |
-				int32(element[1])<<8 |
-				int32(element[2])<<16 |
-				int32(element[3])<<24
-			v.SetMapIndex(key, reflect.ValueOf(element))
-
-		case TypeTimestamp:
-			ts := Timestamp(element[0]) |
-				Timestamp(element[1])<<8 |
-				Timestamp(element[2])<<16 |
-				Timestamp(element[3])<<24 |
-				Timestamp(element[4])<<32 |
-				Timestamp(element[5])<<40 |
-				Timestamp(element[6])<<48 |
-				Timestamp(element[7])<<56
-			v.SetMapIndex(key, reflect.ValueOf(ts))
-
-		case TypeInt64:
-			element := int64(element[0]) |
-				int64(element[1])<<8 |
-				int64(element[2])<<16 |
-				int64(element[3])<<24 |
-				int64(element[4])<<32 |
-				int64(element[5])<<40 |
-				int64(element[6])<<48 |
-				int64(element[7])<<56
-			v.SetMapIndex(key, reflect.ValueOf(element))
-
-		case TypeBinary,
-			TypeUndefined,
-			TypeDateTime,
-			TypeRegex,
-			TypeDBPointer,
-			TypeCodeWithScope,
-			TypeSymbol,
-			TypeJavaScriptScope,
-			TypeDecimal,
-			TypeMinKey,
-			TypeMaxKey:
-			return fmt.Errorf("unsupported type %x", typ)
-
-		default:
-			return fmt.Errorf("unknown element type %x", typ)
+			v.SetMapIndex(key, elem)
+			continue
+		}
+
+		val, err := ds.decodeValue(typ, element)
+		if err != nil {
+			return iter.wrapErr(err)
+		}
+
+		vv := reflect.Zero(elemType)
+		if val != nil {
+			vv = reflect.ValueOf(val)
+		}
+		if !vv.Type().AssignableTo(elemType) {
+			return iter.wrapErr(fmt.Errorf("%w: cannot assign %T to %v", ErrTypeMismatch, val, elemType))
 		}
+		v.SetMapIndex(key, vv)
 	}
 	return iter.Err()
 }
 
-func decodeSlice(data []byte, v *[]any) error {
+// decodeInto decodes the element into a settable value v.
+// Nested documents are decoded into structs and typed maps, arrays into typed slices,
+// other values must be assignable or convertible to the type of v.
+func (ds *decodeState) decodeInto(typ Type, element []byte, v reflect.Value) error {
+	if typ == TypeNull {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return ds.decodeInto(typ, element, v.Elem())
+
+	case reflect.Struct:
+		if typ == TypeDocument {
+			return ds.decodeStruct(element, v)
+		}
+
+	case reflect.Map:
+		if typ == TypeDocument {
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			return ds.decodeMap(element, v)
+		}
+
+	case reflect.Slice:
+		switch {
+		case typ == TypeDocument && v.Type() == typeD:
+			var d D
+			if err := ds.readD(element, &d); err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(d))
+			return nil
+		case typ == TypeArray && v.Type().Elem().Kind() != reflect.Interface:
+			return ds.decodeTypedSlice(element, v)
+		}
+	}
+
+	val, err := ds.decodeValue(typ, element)
+	if err != nil {
+		return err
+	}
+	if bin, ok := val.(bsonproto.Binary); ok && v.Type() == typeBytes {
+		val = bin.B
+	}
+	rv, err := convertValue(val, v.Type())
+	if err != nil {
+		return err
+	}
+	v.Set(rv)
+	return nil
+}
+
+var (
+	typeD     = reflect.TypeOf(D{})
+	typeBytes = reflect.TypeOf([]byte{})
+)
+
+func (ds *decodeState) decodeTypedSlice(data []byte, v reflect.Value) error {
+	if err := ds.enter(); err != nil {
+		return err
+	}
+	defer ds.leave()
+
+	iter, err := newReader(data)
+	if err != nil {
+		return err
+	}
+
+	s := reflect.MakeSlice(v.Type(), 0, 0)
+	for iter.Next() {
+		typ, name, element := iter.Peek()
+
+		elem := reflect.New(v.Type().Elem()).Elem()
+		ds.push(name)
+		err := ds.decodeInto(typ, element, elem)
+		ds.pop()
+		if err != nil {
+			return iter.wrapErr(err)
+		}
+		s = reflect.Append(s, elem)
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	v.Set(s)
+	return nil
+}
+
+func (ds *decodeState) decodeSlice(data []byte, v *[]any) error {
+	if err := ds.enter(); err != nil {
+		return err
+	}
+	defer ds.leave()
+
 	iter, err := newReader(data)
 	if err != nil {
 		return err
@@ -357,97 +528,222 @@ func decodeSlice(data []byte, v *[]any) error {
 	for iter.Next() {
 		typ, _, element := iter.Peek()
 
-		switch typ {
-		case TypeDouble:
-			bits := uint64(element[0]) |
-				uint64(element[1])<<8 |
-				uint64(element[2])<<16 |
-				uint64(element[3])<<24 |
-				uint64(element[4])<<32 |
-				uint64(element[5])<<40 |
-				uint64(element[6])<<48 |
-				uint64(element[7])<<56
-			*v = append(*v, bits)
-
-		case TypeString:
-			*v = append(*v, trimlast(element))
-
-		case TypeDocument:
-			m := make(map[string]any)
-			vv := reflect.ValueOf(m)
-			if err := decodeMap(element, vv); err != nil {
-				return err
-			}
-			*v = append(*v, m)
+		val, err := ds.decodeValue(typ, element)
+		if err != nil {
+			return iter.wrapErr(err)
+		}
+		*v = append(*v, val)
+	}
+	return iter.Err()
+}
 
-		case TypeArray:
-			s := make([]any, 0)
-			if err := decodeSlice(element, &s); err != nil {
-				return err
-			}
-			*v = append(*v, s)
+// rawToD decodes a raw document preserving order of nested documents,
+// depth is the nesting level of the document starting from 1.
+func rawToD(data []byte, depth int) (D, error) {
+	if depth > DefaultMaxDepth {
+		return nil, depthError(DefaultMaxDepth)
+	}
+	iter, err := newReader(data)
+	if err != nil {
+		return nil, err
+	}
 
-		case TypeObjectID:
-			var oid ObjectID
-			copy(oid[:], element)
-			*v = append(*v, oid)
+	d := D{}
+	for iter.Next() {
+		typ, name, element := iter.Peek()
 
-		case TypeBool:
-			b := element[0] == 1
-			*v = append(*v, b)
+		val, err := decodeRawValue(typ, element, depth)
+		if err != nil {
+			return nil, iter.wrapErr(err)
+		}
+		d = append(d, E{Key: trimlast(name), Value: val})
+	}
+	return d, iter.Err()
+}
 
-		// case TypeDateTime:
-		// 	dt := Datetime(element[0]) | Datetime(element[1])<<8 | Datetime(element[2])<<16 | Datetime(element[3])<<24 | Datetime(element[4])<<32 | Datetime(element[5])<<40 | Datetime(element[6])<<48 | Datetime(element[7])<<56
-		// 	*v = append(*v, dt)
+// rawToA decodes a raw array preserving order of nested documents,
+// depth is the nesting level of the array starting from 1.
+func rawToA(data []byte, depth int) (A, error) {
+	if depth > DefaultMaxDepth {
+		return nil, depthError(DefaultMaxDepth)
+	}
+	iter, err := newReader(data)
+	if err != nil {
+		return nil, err
+	}
 
-		case TypeNull:
-			*v = append(*v, nil)
+	a := A{}
+	for iter.Next() {
+		typ, _, element := iter.Peek()
+
+		val, err := decodeRawValue(typ, element, depth)
+		if err != nil {
+			return nil, iter.wrapErr(err)
+		}
+		a = append(a, val)
+	}
+	return a, iter.Err()
+}
+
+// decodeRawValue is like decodeValue but returns D for documents and A for arrays
+// of the given parent depth.
+func decodeRawValue(typ Type, element []byte, depth int) (any, error) {
+	var ds decodeState
+	switch typ {
+	case TypeDocument:
+		return rawToD(element, depth+1)
+	case TypeArray:
+		return rawToA(element, depth+1)
+	default:
+		return ds.decodeValue(typ, element)
+	}
+}
+
+// newRawValue returns a copy of the element read by the reader as [RawValue],
+// the reader drops the length prefix of strings and it's restored here.
+func newRawValue(typ Type, element []byte) RawValue {
+	switch typ {
+	case TypeString, TypeCodeWithScope, TypeSymbol:
+		n := len(element)
+		data := append([]byte{byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24)}, element...)
+		return RawValue{Type: typ, Data: data}
+	default:
+		return RawValue{Type: typ, Data: append([]byte{}, element...)}
+	}
+}
+
+// decodeValue decodes element of the given type into a Go value.
+func (ds *decodeState) decodeValue(typ Type, element []byte) (any, error) {
+	switch typ {
+	case TypeDouble:
+		bits := uint64(element[0]) |
+			uint64(element[1])<<8 |
+			uint64(element[2])<<16 |
+			uint64(element[3])<<24 |
+			uint64(element[4])<<32 |
+			uint64(element[5])<<40 |
+			uint64(element[6])<<48 |
+			uint64(element[7])<<56
+		return math.Float64frombits(bits), nil
+
+	case TypeString:
+		return ds.str(element)
 
-		case TypeInt32:
-			element := int32(element[0]) | int32(element[1])<<8 | int32(element[2])<<16 | int32(element[3])<<24
-			*v = append(*v, element)
+	case TypeDocument:
+		return ds.decodeAnyDocument(element)
 
-		case TypeTimestamp:
-			ts := Timestamp(element[0]) | Timestamp(element[1])<<8 | Timestamp(element[2])<<16 | Timestamp(element[3])<<24 | Timestamp(element[4])<<32 | Timestamp(element[5])<<40 | Timestamp(element[6])<<48 | Timestamp(element[7])<<56
-			*v = append(*v, ts)
+	case TypeArray:
+		return ds.decodeAnyArray(element)
 
-		case TypeInt64:
-			element := int64(element[0]) | int64(element[1])<<8 | int64(element[2])<<16 | int64(element[3])<<24 | int64(element[4])<<32 | int64(element[5])<<40 | int64(element[6])<<48 | int64(element[7])<<56
-			*v = append(*v, element)
+	case TypeObjectID:
+		var oid ObjectID
+		copy(oid[:], element)
+		return oid, nil
 
-		case TypeBinary,
-			TypeUndefined,
-			TypeDateTime,
-			TypeRegex,
-			TypeDBPointer,
-			TypeCodeWithScope,
-			TypeSymbol,
-			TypeJavaScriptScope,
-			TypeDecimal,
-			TypeMinKey,
-			TypeMaxKey:
-			return fmt.Errorf("unsupported type %x", typ)
+	case TypeBool:
+		return element[0] == 1, nil
 
-		default:
-			return fmt.Errorf("unknown element type %x", typ)
+	case TypeDateTime:
+		ms := int64(element[0]) |
+			int64(element[1])<<8 |
+			int64(element[2])<<16 |
+			int64(element[3])<<24 |
+			int64(element[4])<<32 |
+			int64(element[5])<<40 |
+			int64(element[6])<<48 |
+			int64(element[7])<<56
+		return time.UnixMilli(ms).UTC(), nil
+
+	case TypeNull:
+		return nil, nil
+
+	case TypeInt32:
+		return int32(element[0]) |
+			int32(element[1])<<8 |
+			int32(element[2])<<16 |
+			int32(element[3])<<24, nil
+
+	case TypeTimestamp:
+		return Timestamp(element[0]) |
+			Timestamp(element[1])<<8 |
+			Timestamp(element[2])<<16 |
+			Timestamp(element[3])<<24 |
+			Timestamp(element[4])<<32 |
+			Timestamp(element[5])<<40 |
+			Timestamp(element[6])<<48 |
+			Timestamp(element[7])<<56, nil
+
+	case TypeInt64:
+		return int64(element[0]) |
+			int64(element[1])<<8 |
+			int64(element[2])<<16 |
+			int64(element[3])<<24 |
+			int64(element[4])<<32 |
+			int64(element[5])<<40 |
+			int64(element[6])<<48 |
+			int64(element[7])<<56, nil
+
+	case TypeBinary:
+		bin := bsonproto.Binary{Subtype: bsonproto.BinarySubtype(element[4])}
+		if len(element) > 5 {
+			bin.B = append([]byte(nil), element[5:]...)
+		}
+		return bin, nil
+
+	case TypeRegex:
+		var re Regex
+		if err := re.UnmarshalBSON(element); err != nil {
+			return nil, err
 		}
+		return re, nil
+
+	case TypeDecimal:
+		return bsonproto.DecodeDecimal128(element)
+
+	case TypeMinKey:
+		return MinKey{}, nil
+
+	case TypeMaxKey:
+		return MaxKey{}, nil
+
+	case TypeUndefined,
+		TypeDBPointer,
+		TypeCodeWithScope,
+		TypeSymbol,
+		TypeJavaScriptScope:
+		// deprecated types are kept as is, so they can be encoded back.
+		return newRawValue(typ, element), nil
+
+	default:
+		return nil, fmt.Errorf("%w: unknown element type %#x", ErrInvalidType, byte(typ))
 	}
-	return iter.Err()
 }
 
 type reader struct {
 	data    []byte // data to process.
 	name    []byte // name of the current element.
 	element []byte // current element.
+	size    int    // size of the document.
+	pos     int    // offset of the current element in the document.
 	err     error  // err during reading.
 }
 
 func newReader(b []byte) (reader, error) {
 	if len(b) < 5 {
-		return reader{}, errors.New("not enough data")
+		return reader{}, &DecodeError{Err: fmt.Errorf("%w: have %d bytes", ErrShortInput, len(b))}
+	}
+	size, _ := readInt32(b)
+	switch {
+	case size < 5 || size < len(b):
+		return reader{}, &DecodeError{Err: fmt.Errorf("%w: invalid document size %d, have %d bytes", ErrInvalidInput, size, len(b))}
+	case size > len(b):
+		return reader{}, &DecodeError{Err: fmt.Errorf("%w: document size %d, have %d bytes", ErrShortInput, size, len(b))}
+	case b[len(b)-1] != 0:
+		return reader{}, &DecodeError{Offset: len(b) - 1, Err: fmt.Errorf("%w: document is not terminated with 0x00", ErrInvalidInput)}
 	}
 	return reader{
-		data: b[4 : len(b)-1], // TODO(cristaloleg): this can easily panic.
+		data: b[4 : len(b)-1],
+		size: len(b),
 	}, nil
 }
 
@@ -463,126 +759,210 @@ func (r *reader) Next() bool {
 	if r.err != nil || len(r.data) == 0 {
 		return false
 	}
+	r.pos = r.size - 1 - len(r.data)
+
 	if len(r.data) == 1 {
-		return r.setErr(errors.New("corrupt BSON, only 1 byte remains"))
+		return r.setErr(0, "", fmt.Errorf("%w: only 1 byte remains", ErrInvalidInput))
 	}
 
+	typ := Type(r.data[0])
 	i := bytes.IndexByte(r.data[1:], 0)
 	if i < 0 {
-		return r.setErr(errors.New("corrupt BSON name"))
+		return r.setErr(typ, "", fmt.Errorf("%w: element name is not terminated with 0x00", ErrInvalidInput))
 	}
 	i += 2
 
 	name, rest := r.data[:i], r.data[i:]
 	var element []byte
+	var err error
 
-	switch typ := name[0]; Type(typ) {
+	switch typ {
 	case TypeDouble:
-		if len(rest) < 8 {
-			return r.setErr(errors.New("corrupt BSON reading double"))
-		}
-		element, rest = rest[:8], rest[8:]
+		element, rest, err = readFixed(rest, 8)
 
-	case TypeString:
-		if len(rest) < 5 {
-			return r.setErr(errors.New("corrupt BSON reading string len"))
-		}
-		elen, _ := readInt32(rest)
-		if len(rest) < elen {
-			return r.setErr(errors.New("corrupt BSON reading string"))
-		}
-		element = rest[4 : 4+elen]
-		if len(rest) < 4+elen {
-			return r.setErr(errors.New("corrupt BSON reading string"))
-		}
-		rest = rest[4+elen:]
+	case TypeString, TypeCodeWithScope, TypeSymbol:
+		element, rest, err = readString(rest)
 
 	case TypeDocument, TypeArray:
-		if len(rest) < 5 {
-			return r.setErr(errors.New("corrupt BSON reading string len"))
-		}
-		elen, _ := readInt32(rest)
-		if len(rest) < elen {
-			return r.setErr(fmt.Errorf("corrupt document: want %x bytes, have %x", elen, len(rest)))
-		}
-		element, rest = rest[:elen], rest[elen:]
+		element, rest, err = readDocument(rest)
 
 	case TypeObjectID:
-		if len(rest) < 12 {
-			return r.setErr(errors.New("corrupt BSON reading object id"))
-		}
-		element, rest = rest[:12], rest[12:]
+		element, rest, err = readFixed(rest, 12)
 
 	case TypeBool:
-		if len(rest) < 1 {
-			return r.setErr(errors.New("corrupt BSON reading boolean"))
+		element, rest, err = readFixed(rest, 1)
+		if err == nil && element[0] > 1 {
+			err = fmt.Errorf("%w: invalid boolean value %d", ErrInvalidInput, element[0])
 		}
-		element, rest = rest[:1], rest[1:]
 
 	case TypeDateTime:
-		if len(rest) < 8 {
-			return r.setErr(errors.New("corrupt BSON reading utc datetime"))
-		}
-		element, rest = rest[:8], rest[8:]
+		element, rest, err = readFixed(rest, 8)
 
-	case TypeNull:
-		element, rest = rest[:0], rest[0:]
-
-	// TODO(cristaloleg): fuzzer fails on 2nd IndexByte.
-	// case TypeRegex:
-	// 	if len(rest) < 2 {
-	// 		return r.setErr(errors.New("corrupt BSON reading regex"))
-	// 	}
-	// 	i := bytes.IndexByte(rest, 0)
-	// 	if i < 0 {
-	// 		return r.setErr(errors.New("corrupt BSON regex 1"))
-	// 	}
-	// 	i++
-	// 	j := bytes.IndexByte(rest[i+1:], 0)
-	// 	if j < 0 {
-	// 		return r.setErr(errors.New("corrupt BSON regex 2"))
-	// 	}
-	// 	j++
-	// 	element, rest = rest[:i+j+1], rest[i+j+1:]
+	case TypeNull, TypeUndefined, TypeMinKey, TypeMaxKey:
+		element = rest[:0]
 
-	case TypeInt32:
-		if len(rest) < 4 {
-			return r.setErr(errors.New("corrupt BSON reading int32"))
-		}
-		element, rest = rest[:4], rest[4:]
+	case TypeBinary:
+		element, rest, err = readBinary(rest)
 
-	case TypeTimestamp:
-		fallthrough
+	case TypeRegex:
+		element, rest, err = readRegex(rest)
 
-	case TypeInt64:
-		if len(rest) < 8 {
-			return r.setErr(errors.New("corrupt BSON reading int64"))
+	case TypeDBPointer:
+		var str []byte
+		str, rest, err = readString(rest)
+		if err == nil {
+			var oid []byte
+			oid, rest, err = readFixed(rest, 12)
+			element = r.data[i : i+4+len(str)+len(oid)]
 		}
-		element, rest = rest[:8], rest[8:]
+
+	case TypeJavaScriptScope:
+		element, rest, err = readCodeWithScope(rest)
+
+	case TypeDecimal:
+		element, rest, err = readFixed(rest, 16)
+
+	case TypeInt32:
+		element, rest, err = readFixed(rest, 4)
+
+	case TypeTimestamp, TypeInt64:
+		element, rest, err = readFixed(rest, 8)
 
 	default:
-		return r.setErr(fmt.Errorf("unknown element type %x", typ))
+		err = fmt.Errorf("%w: unknown element type %#x", ErrInvalidType, byte(typ))
+	}
+
+	if err != nil {
+		return r.setErr(typ, trimlast(name[1:]), err)
 	}
 
 	r.data, r.name, r.element = rest, name, element
 	return true
 }
 
-func (r *reader) setErr(err error) bool {
+// setErr sets the error for the element at the current position.
+func (r *reader) setErr(typ Type, key string, err error) bool {
 	if r.err == nil {
-		r.err = err
+		r.err = &DecodeError{Offset: r.pos, Type: typ, Path: key, Err: err}
 	}
 	return false
 }
 
+// wrapErr annotates an error returned for the current element with its position and key.
+func (r *reader) wrapErr(err error) error {
+	typ, name, _ := r.Peek()
+	if de, ok := err.(*DecodeError); ok {
+		return de.withParent(r.pos+len(r.name), typ, trimlast(name))
+	}
+	return &DecodeError{Offset: r.pos, Type: typ, Path: trimlast(name), Err: err}
+}
+
+// readFixed returns an element of the fixed size.
+func readFixed(b []byte, size int) ([]byte, []byte, error) {
+	if len(b) < size {
+		return nil, nil, fmt.Errorf("%w: want %d bytes, have %d", ErrShortInput, size, len(b))
+	}
+	return b[:size], b[size:], nil
+}
+
+// readString returns a string element without length prefix but with trailing 0x00.
+func readString(b []byte) ([]byte, []byte, error) {
+	if len(b) < 5 {
+		return nil, nil, fmt.Errorf("%w: reading string", ErrShortInput)
+	}
+	elen, rest := readInt32(b)
+	switch {
+	case elen < 1:
+		return nil, nil, fmt.Errorf("%w: invalid string length %d", ErrInvalidInput, elen)
+	case len(rest) < elen:
+		return nil, nil, fmt.Errorf("%w: string length %d, have %d bytes", ErrShortInput, elen, len(rest))
+	case rest[elen-1] != 0:
+		return nil, nil, fmt.Errorf("%w: string is not terminated with 0x00", ErrInvalidInput)
+	}
+	return rest[:elen], rest[elen:], nil
+}
+
+// readDocument returns an embedded document or array including length prefix.
+func readDocument(b []byte) ([]byte, []byte, error) {
+	if len(b) < 5 {
+		return nil, nil, fmt.Errorf("%w: reading document", ErrShortInput)
+	}
+	elen, _ := readInt32(b)
+	switch {
+	case elen < 5:
+		return nil, nil, fmt.Errorf("%w: invalid document size %d", ErrInvalidInput, elen)
+	case len(b) < elen:
+		return nil, nil, fmt.Errorf("%w: document size %d, have %d bytes", ErrShortInput, elen, len(b))
+	case b[elen-1] != 0:
+		return nil, nil, fmt.Errorf("%w: document is not terminated with 0x00", ErrInvalidInput)
+	}
+	return b[:elen], b[elen:], nil
+}
+
+// readBinary returns a binary element including length prefix and subtype.
+func readBinary(b []byte) ([]byte, []byte, error) {
+	if len(b) < 5 {
+		return nil, nil, fmt.Errorf("%w: reading binary", ErrShortInput)
+	}
+	elen, _ := readInt32(b)
+	switch {
+	case elen < 0:
+		return nil, nil, fmt.Errorf("%w: invalid binary length %d", ErrInvalidInput, elen)
+	case len(b)-5 < elen:
+		return nil, nil, fmt.Errorf("%w: binary length %d, have %d bytes", ErrShortInput, elen, len(b)-5)
+	}
+	return b[:5+elen], b[5+elen:], nil
+}
+
+// readRegex returns a regex element: pattern and options cstrings.
+func readRegex(b []byte) ([]byte, []byte, error) {
+	i := bytes.IndexByte(b, 0)
+	if i < 0 {
+		return nil, nil, fmt.Errorf("%w: regex pattern is not terminated with 0x00", ErrInvalidInput)
+	}
+	j := bytes.IndexByte(b[i+1:], 0)
+	if j < 0 {
+		return nil, nil, fmt.Errorf("%w: regex options are not terminated with 0x00", ErrInvalidInput)
+	}
+	n := i + 1 + j + 1
+	return b[:n], b[n:], nil
+}
+
+// readCodeWithScope returns code with scope element including total length prefix.
+func readCodeWithScope(b []byte) ([]byte, []byte, error) {
+	if len(b) < 4 {
+		return nil, nil, fmt.Errorf("%w: reading code with scope", ErrShortInput)
+	}
+	elen, rest := readInt32(b)
+	switch {
+	case elen < 14:
+		return nil, nil, fmt.Errorf("%w: invalid code with scope length %d", ErrInvalidInput, elen)
+	case len(b) < elen:
+		return nil, nil, fmt.Errorf("%w: code with scope length %d, have %d bytes", ErrShortInput, elen, len(b))
+	}
+
+	code, rest, err := readString(rest[:elen-4])
+	if err != nil {
+		return nil, nil, err
+	}
+	scope, rest, err := readDocument(rest)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rest) != 0 || 4+4+len(code)+len(scope) != elen {
+		return nil, nil, fmt.Errorf("%w: code with scope length mismatch", ErrInvalidInput)
+	}
+	return b[:elen], b[elen:], nil
+}
+
 // readInt32 the 4 bytes in little endian and return tail.
 // Panics if less than 4 bytes is passed.
 func readInt32(buf []byte) (int, []byte) {
-	v := int(buf[0]) |
-		int(buf[1])<<8 |
-		int(buf[2])<<16 |
-		int(buf[3])<<24
-	return v, buf[4:]
+	v := int32(buf[0]) |
+		int32(buf[1])<<8 |
+		int32(buf[2])<<16 |
+		int32(buf[3])<<24
+	return int(v), buf[4:]
 }
 
 // readCstring returns CString including \0.
@@ -1,8 +1,15 @@
 package bson
 
 import (
+	"bytes"
 	"encoding/hex"
+	"errors"
+	"io"
+	"strconv"
 	"testing"
+	"time"
+
+	"github.com/cristalhq/bson/bsonproto"
 )
 
 func TestDecode(t *testing.T) {
@@ -28,6 +35,47 @@ func TestDecode(t *testing.T) {
 	t.Logf("got %+v\n", doc)
 }
 
+func TestDecodeValues(t *testing.T) {
+	oid := ObjectID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+	date := time.UnixMilli(1700000000000).UTC()
+	values := []any{
+		1.5,
+		"str",
+		oid,
+		true,
+		date,
+		nil,
+		int32(1),
+		Timestamp(2),
+		int64(3),
+		bsonproto.Binary{Subtype: bsonproto.BinaryUUID, B: []byte{1, 2}},
+		Regex{Pattern: "a", Options: "i"},
+		bsonproto.Decimal128{L: 1},
+		MinKey{},
+		MaxKey{},
+	}
+
+	doc := D{}
+	for i, v := range values {
+		doc = append(doc, E{Key: strconv.Itoa(i), Value: v})
+	}
+	doc = append(doc, E{Key: "arr", Value: A(values)})
+	raw := must(Marshal(doc))
+
+	var d D
+	mustOk(t, Unmarshal(raw, &d))
+	for i, v := range values {
+		mustDeepEqual(t, d[i].Value, v)
+	}
+	mustDeepEqual(t, d[len(values)].Value, values)
+
+	var m map[string]any
+	mustOk(t, Unmarshal(raw, &m))
+	mustDeepEqual(t, m["0"], 1.5)
+	mustDeepEqual(t, m["2"], oid)
+	mustDeepEqual(t, m["arr"], values)
+}
+
 func Benchmark_cristalhq_Unmarshal(b *testing.B) {
 	buf, _ := hex.DecodeString("a001000002616e6e6f756e636500270000007564703a2f2f747261636b65722e7075626c696362742e636f6d3a38302f616e6e6f756e63650004616e6e6f756e63656c69737400cf000000023000270000007564703a2f2f747261636b65722e7075626c696362742e636f6d3a38302f616e6e6f756e6365000231002d0000007564703a2f2f747261636b65722e6f70656e626974746f7272656e742e636f6d3a38302f616e6e6f756e6365000232002d0000007564703a2f2f747261636b65722e6f70656e626974746f7272656e742e636f6d3a38302f616e6e6f756e6365000233002d0000007564703a2f2f747261636b65722e6f70656e626974746f7272656e742e636f6d3a38302f616e6e6f756e6365000002636f6d6d656e74002200000044656269616e2043442066726f6d206364696d6167652e64656269616e2e6f72670003696e666f0054000000126c656e677468000000300a00000000026e616d65001f00000064656269616e2d382e382e302d61726d36342d6e6574696e73742e69736f00127069656365206c656e6774680000000400000000000000")
 	b.ReportAllocs()
@@ -48,11 +96,60 @@ func Fuzz_Decoder2(f *testing.F) {
 	f.Add(unhex("a001000002616e6e6f756e636500270000007564703a2f2f747261636b65722e7075626c696362742e636f6d3a38302f616e6e6f756e63650004616e6e6f756e63656c69737400cf000000023000270000007564703a2f2f747261636b65722e7075626c696362742e636f6d3a38302f616e6e6f756e6365000231002d0000007564703a2f2f747261636b65722e6f70656e626974746f7272656e742e636f6d3a38302f616e6e6f756e6365000232002d0000007564703a2f2f747261636b65722e6f70656e626974746f7272656e742e636f6d3a38302f616e6e6f756e6365000233002d0000007564703a2f2f747261636b65722e6f70656e626974746f7272656e742e636f6d3a38302f616e6e6f756e6365000002636f6d6d656e74002200000044656269616e2043442066726f6d206364696d6167652e64656269616e2e6f72670003696e666f0054000000126c656e677468000000300a00000000026e616d65001f00000064656269616e2d382e382e302d61726d36342d6e6574696e73742e69736f00127069656365206c656e6774680000000400000000000000"))
 	f.Add(unhex("4d88e15b60f486e428412dc9"))
 
-	v := map[string]any{}
+	f.Add(unhex("0500000000"))
+	f.Add(unhex("42000000" + "067500" + "0c7000" + "030000006e7300" + "0102030405060708090a0b0c" +
+		"0d6300" + "020000006100" + "0e7300" + "020000007300" +
+		"0f7700" + "0f000000" + "020000006100" + "0500000000" + "00"))
+	f.Add(must(Marshal(D{
+		{"a", A{int32(1), "x", D{{"b", nil}}}},
+		{"bin", []byte{1, 2}},
+		{"re", Regex{Pattern: "a", Options: "i"}},
+		{"ts", Timestamp(1)},
+		{"dec", decimal(1, 0)},
+		{"min", MinKey{}},
+	})))
+
+	type nested struct {
+		B any `bson:"b"`
+	}
+	type record struct {
+		A    []any          `bson:"a"`
+		N    int64          `bson:"n"`
+		S    string         `bson:"s"`
+		Sub  nested         `bson:"sub"`
+		List []nested       `bson:"list"`
+		Map  map[string]int `bson:"map"`
+	}
+
 	f.Fuzz(func(t *testing.T, buf []byte) {
-		dec := NewDecodeBytes(buf)
-		if err := dec.Decode(v); err != nil {
+		var m map[string]any
+		_ = Unmarshal(buf, &m)
+
+		var d D
+		err := Unmarshal(buf, &d)
+		if Validate(buf, nil) == nil && err != nil {
+			t.Fatalf("%x: valid document, unmarshal failed: %v", buf, err)
+		}
+
+		var mm M
+		_ = Unmarshal(buf, &mm)
+
+		var r record
+		_ = Unmarshal(buf, &r)
+
+		dec := NewDecoder(bytes.NewReader(buf))
+		for dec.Decode(&m) == nil {
+		}
+
+		it := DecodeAll[D](NewDecodeBytes(buf))
+		for it.Next() {
 		}
+
+		_, _ = RawObject(buf).ToD()
+		_, _ = RawArray(buf).ToA()
+		_, _ = Lookup[any](buf, "a", "0")
+		_, _ = Project(RawObject(buf), D{{"a", int32(1)}})
+		_ = RawObject(buf).HasDuplicateKeys()
 	})
 }
 
@@ -63,3 +160,258 @@ func unhex(s string) []byte {
 	}
 	return b
 }
+
+func TestUnmarshalAs(t *testing.T) {
+	raw := must(Marshal(D{{"a", int32(1)}, {"b", "str"}}))
+
+	doc, err := UnmarshalAs[D](raw)
+	mustOk(t, err)
+	mustDeepEqual(t, doc, D{{"a", int32(1)}, {"b", "str"}})
+
+	m, err := UnmarshalAs[M](raw)
+	mustOk(t, err)
+	mustDeepEqual(t, m, M{"a": int32(1), "b": "str"})
+
+	type foo struct {
+		A int32
+		B string
+	}
+	raw = must(Marshal(D{{"A", int32(1)}, {"B", "str"}}))
+	s, err := UnmarshalAs[foo](raw)
+	mustOk(t, err)
+	mustEqual(t, s, foo{A: 1, B: "str"})
+
+	_, err = UnmarshalAs[D](nil)
+	mustEqual(t, errors.Is(err, io.ErrUnexpectedEOF), true)
+
+	two := append(must(Marshal(D{{"a", int32(1)}})), raw...)
+	_, err = UnmarshalAs[D](two)
+	mustEqual(t, errors.Is(err, ErrInvalidInput), true)
+	mustEqual(t, err.Error(), "bson: decode error at offset 12: bson: invalid input: 23 trailing bytes after the document")
+
+	_, err = UnmarshalAs[D](append(must(Marshal(D{})), 0))
+	mustFail(t, err)
+
+	it := DecodeAll[D](NewDecodeBytes(two))
+	for it.Next() {
+	}
+	mustOk(t, it.Err())
+}
+
+func TestDecodeAll(t *testing.T) {
+	var buf bytes.Buffer
+	for i := int32(0); i < 3; i++ {
+		buf.Write(must(Marshal(D{{"i", i}})))
+	}
+	data := buf.Bytes()
+
+	for name, dec := range map[string]*Decoder{
+		"bytes":  NewDecodeBytes(data),
+		"reader": NewDecoder(bytes.NewReader(data)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var got []int32
+			it := DecodeAll[M](dec)
+			for it.Next() {
+				got = append(got, it.Value()["i"].(int32))
+			}
+			mustOk(t, it.Err())
+			mustDeepEqual(t, got, []int32{0, 1, 2})
+		})
+	}
+
+	t.Run("truncated", func(t *testing.T) {
+		it := DecodeAll[D](NewDecoder(bytes.NewReader(data[:len(data)-3])))
+		var n int
+		for it.Next() {
+			n++
+		}
+		mustEqual(t, n, 2)
+		mustEqual(t, errors.Is(it.Err(), io.ErrUnexpectedEOF), true)
+	})
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	// doc wraps hex encoded elements into a document with a valid size and terminator.
+	doc := func(body string) []byte {
+		b := unhex(body)
+		n := len(b) + 5
+		return append(append([]byte{byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24)}, b...), 0)
+	}
+
+	testCases := [][]byte{
+		unhex("04000000"),
+		unhex("0400000000"),
+		unhex("0a00000000"),
+		unhex("ffffffff00"),
+		unhex("0500000001"),
+		doc("1061"),
+		doc("10610001"),
+		doc("0261000000000000"),
+		doc("026100ffffffff00"),
+		doc("0261000a0000006100"),
+		doc("026100020000006161"),
+		doc("036100040000000000"),
+		doc("0361000600000000"),
+		doc("0361000500000001"),
+		doc("04610005000000" + "01"),
+		doc("08610002"),
+		doc("05610000ffffff"),
+		doc("056100ffffffff00"),
+		doc("0b610061"),
+		doc("0c610002000000610001"),
+		doc("0f6100" + "0e000000" + "020000006100" + "0500000000"),
+		doc("0f6100" + "ff000000" + "020000006100" + "0500000000"),
+		doc("136100010203"),
+		doc("206100"),
+	}
+
+	for _, data := range testCases {
+		var m map[string]any
+		if err := Unmarshal(data, &m); err == nil {
+			t.Fatalf("%x: must fail", data)
+		}
+		var d D
+		if err := Unmarshal(data, &d); err == nil {
+			t.Fatalf("%x: must fail", data)
+		}
+		if _, err := (RawObject(data)).ToD(); err == nil {
+			t.Fatalf("%x: must fail", data)
+		}
+	}
+}
+
+func TestDecodeTypeMismatch(t *testing.T) {
+	type record struct {
+		N    int             `bson:"n"`
+		Sub  struct{ X int } `bson:"sub"`
+		List []string        `bson:"list"`
+	}
+
+	var r record
+	mustOk(t, Unmarshal(must(Marshal(D{
+		{"n", int64(1)},
+		{"sub", D{{"X", int32(2)}}},
+		{"list", A{"a", "b"}},
+	})), &r))
+	mustDeepEqual(t, r, record{N: 1, Sub: struct{ X int }{X: 2}, List: []string{"a", "b"}})
+
+	mustFail(t, Unmarshal(must(Marshal(D{{"n", "str"}})), &r))
+	mustFail(t, Unmarshal(must(Marshal(D{{"sub", "str"}})), &r))
+	mustFail(t, Unmarshal(must(Marshal(D{{"list", A{int32(1)}}})), &r))
+
+	err := Unmarshal(must(Marshal(D{{"n", 3.7}})), &r)
+	mustEqual(t, errors.Is(err, ErrTypeMismatch), true)
+
+	var m map[string]int
+	mustFail(t, Unmarshal(must(Marshal(D{{"a", "str"}})), &m))
+
+	var small map[string]uint8
+	err = Unmarshal(must(Marshal(D{{"a", int32(300)}})), &small)
+	mustEqual(t, errors.Is(err, ErrTypeMismatch), true)
+
+	var bad map[int]any
+	mustFail(t, Unmarshal(must(Marshal(D{{"a", "str"}})), &bad))
+}
+
+func TestDecodeStructFields(t *testing.T) {
+	type record struct {
+		Name    string `bson:"name"`
+		Skip    string `bson:"-"`
+		Renamed int32  `bson:"other"`
+		Plain   int32
+	}
+
+	var r record
+	mustOk(t, Unmarshal(must(Marshal(D{
+		{"name", "john"},
+		{"other", int32(2)},
+		{"plain", int32(4)},
+	})), &r))
+	mustEqual(t, r, record{Name: "john", Renamed: 2, Plain: 4})
+
+	r = record{}
+	mustOk(t, Unmarshal(must(Marshal(D{
+		{"Name", "x"},
+		{"Skip", "y"},
+		{"Renamed", int32(1)},
+		{"Plain", int32(3)},
+	})), &r))
+	mustEqual(t, r, record{Name: "x", Renamed: 1, Plain: 3})
+
+	type dup struct {
+		A int32 `bson:"x"`
+		B int32 `bson:"x"`
+	}
+	var d dup
+	mustFail(t, Unmarshal(must(Marshal(D{{"x", int32(1)}})), &d))
+	_, err := Marshal(dup{})
+	mustFail(t, err)
+	_, err = Marshal(D{{"a", dup{}}})
+	mustFail(t, err)
+}
+
+func TestDecodeError(t *testing.T) {
+	raw := must(Marshal(D{
+		{"a", "x"},
+		{"items", A{
+			D{{"price", int32(1)}},
+			D{{"price", int32(2)}},
+		}},
+	}))
+	// replace the type of items.1.price (int32) with an unknown type.
+	idx := bytes.LastIndex(raw, []byte("\x10price\x00"))
+	raw[idx] = 0x20
+
+	var d D
+	err := Unmarshal(raw, &d)
+
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("want DecodeError, got %v", err)
+	}
+	mustEqual(t, de.Offset, idx)
+	mustEqual(t, de.Path, "items.1.price")
+	mustEqual(t, de.Type, Type(0x20))
+	mustEqual(t, errors.Is(err, ErrInvalidType), true)
+
+	raw = must(Marshal(D{{"a", "x"}, {"b", int32(1)}}))
+	err = Unmarshal(raw[:len(raw)-2], &d)
+	mustEqual(t, errors.Is(err, ErrShortInput), true)
+	mustEqual(t, errors.Is(err, bsonproto.ErrDecodeShortInput), true)
+	mustEqual(t, errors.Is(err, ErrInvalidInput), false)
+
+	raw[len(raw)-1] = 1
+	err = Unmarshal(raw, &d)
+	mustEqual(t, errors.Is(err, ErrInvalidInput), true)
+	mustEqual(t, errors.Is(err, bsonproto.ErrDecodeInvalidInput), true)
+	mustEqual(t, err.Error(), "bson: decode error at offset 20: bson: invalid input: document is not terminated with 0x00")
+
+	type record struct {
+		Items []struct {
+			Price string `bson:"price"`
+		} `bson:"items"`
+	}
+	raw = must(Marshal(D{{"items", A{D{{"price", "1"}}, D{{"price", int32(2)}}}}}))
+	var r record
+	err = Unmarshal(raw, &r)
+	if !errors.As(err, &de) {
+		t.Fatalf("want DecodeError, got %v", err)
+	}
+	mustEqual(t, de.Path, "items.1.price")
+	mustEqual(t, de.Type, TypeInt32)
+	mustEqual(t, errors.Is(err, ErrTypeMismatch), true)
+	mustEqual(t, raw[de.Offset], byte(TypeInt32))
+
+	// offsets are counted from the start of the stream.
+	first := must(Marshal(D{{"a", int32(1)}}))
+	second := must(Marshal(D{{"a", int32(1)}}))
+	second[4] = 0x20
+	dec := NewDecodeBytes(append(first, second...))
+	mustOk(t, dec.Decode(&d))
+	err = dec.Decode(&d)
+	if !errors.As(err, &de) {
+		t.Fatalf("want DecodeError, got %v", err)
+	}
+	mustEqual(t, de.Offset, len(first)+4)
+}
@@ -0,0 +1,201 @@
+package bson
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ChangeKind represents a kind of a [Change].
+type ChangeKind int
+
+const (
+	ChangeAdded ChangeKind = iota + 1
+	ChangeRemoved
+	ChangeModified
+)
+
+// String implements [fmt.Stringer].
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	default:
+		return "ChangeKind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
+
+// Change is a single difference between two documents.
+type Change struct {
+	Kind ChangeKind
+	// Path is a dot-notation path of the changed element.
+	Path string
+	// Index of the changed array element or -1 if the element is a document field.
+	Index int
+	// Old value, nil for added elements.
+	Old any
+	// New value, nil for removed elements.
+	New any
+}
+
+// DiffOptions configures [Diff] and [DiffUpdate].
+type DiffOptions struct {
+	// StrictNumbers treats numbers of different types as different values,
+	// by default int32(1), int64(1) and 1.0 are equal as in MongoDB.
+	StrictNumbers bool
+}
+
+// Diff returns changes required to turn the old document into the new one.
+// Nested documents and arrays are compared element by element,
+// fields removed from the old document come first, added fields come last.
+//
+// opts can be nil.
+func Diff(old, new D, opts *DiffOptions) []Change {
+	if opts == nil {
+		opts = &DiffOptions{}
+	}
+	var changes []Change
+	diffDocs(&changes, "", old, new, opts)
+	return changes
+}
+
+// DiffUpdate returns a minimal update with $set and $unset operators
+// which turns the old document into the new one when applied.
+// Arrays which lost elements are replaced as a whole.
+//
+// opts can be nil.
+func DiffUpdate(old, new D, opts *DiffOptions) D {
+	changes := Diff(old, new, opts)
+
+	// $unset on array element sets it to null, arrays must be replaced.
+	var arrays []string
+	for _, c := range changes {
+		if c.Kind == ChangeRemoved && c.Index >= 0 {
+			arrays = append(arrays, c.Path[:strings.LastIndexByte(c.Path, '.')])
+		}
+	}
+
+	// nested arrays are replaced with their parents.
+	var replaced []string
+	for _, path := range arrays {
+		if hasPathPrefix(replaced, path) {
+			continue
+		}
+		nested := false
+		for _, p := range arrays {
+			nested = nested || strings.HasPrefix(path, p+".")
+		}
+		if !nested {
+			replaced = append(replaced, path)
+		}
+	}
+
+	set, unset := D{}, D{}
+	for _, path := range replaced {
+		v, _ := GetPath(new, path)
+		set = append(set, E{Key: path, Value: v})
+	}
+	for _, c := range changes {
+		switch {
+		case hasPathPrefix(replaced, c.Path):
+		case c.Kind == ChangeRemoved:
+			unset = append(unset, E{Key: c.Path, Value: ""})
+		default:
+			set = append(set, E{Key: c.Path, Value: c.New})
+		}
+	}
+
+	update := D{}
+	if len(set) > 0 {
+		update = append(update, E{Key: "$set", Value: set})
+	}
+	if len(unset) > 0 {
+		update = append(update, E{Key: "$unset", Value: unset})
+	}
+	return update
+}
+
+func diffDocs(changes *[]Change, prefix string, old, new D, opts *DiffOptions) {
+	for _, elem := range old {
+		path := joinPath(prefix, elem.Key)
+		v, ok := new.Get(elem.Key)
+		if !ok {
+			*changes = append(*changes, Change{Kind: ChangeRemoved, Path: path, Index: -1, Old: elem.Value})
+			continue
+		}
+		diffValues(changes, path, -1, elem.Value, v, opts)
+	}
+
+	for _, elem := range new {
+		if !old.Has(elem.Key) {
+			path := joinPath(prefix, elem.Key)
+			*changes = append(*changes, Change{Kind: ChangeAdded, Path: path, Index: -1, New: elem.Value})
+		}
+	}
+}
+
+func diffArrays(changes *[]Change, prefix string, old, new A, opts *DiffOptions) {
+	for i := 0; i < len(old) || i < len(new); i++ {
+		path := joinPath(prefix, strconv.Itoa(i))
+		switch {
+		case i >= len(new):
+			*changes = append(*changes, Change{Kind: ChangeRemoved, Path: path, Index: i, Old: old[i]})
+		case i >= len(old):
+			*changes = append(*changes, Change{Kind: ChangeAdded, Path: path, Index: i, New: new[i]})
+		default:
+			diffValues(changes, path, i, old[i], new[i], opts)
+		}
+	}
+}
+
+func diffValues(changes *[]Change, path string, index int, old, new any, opts *DiffOptions) {
+	if a, ok := asDocument(old); ok {
+		if b, ok := asDocument(new); ok {
+			diffDocs(changes, path, a, b, opts)
+			return
+		}
+	}
+	if a, ok := asArray(old); ok {
+		if b, ok := asArray(new); ok {
+			diffArrays(changes, path, a, b, opts)
+			return
+		}
+	}
+
+	if !equalValues(old, new, opts.StrictNumbers) {
+		*changes = append(*changes, Change{Kind: ChangeModified, Path: path, Index: index, Old: old, New: new})
+	}
+}
+
+// equalValues reports whether scalar values are equal.
+// Values which cannot be represented in BSON are not equal to anything.
+func equalValues(a, b any, strictNumbers bool) bool {
+	oa, _ := canonicalOrder(a)
+	if oa == orderInvalid || Compare(a, b) != 0 {
+		return false
+	}
+	if !strictNumbers || oa != orderNumber {
+		return true
+	}
+	return reflect.TypeOf(a) == reflect.TypeOf(b)
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func hasPathPrefix(prefixes []string, path string) bool {
+	for _, p := range prefixes {
+		if path == p || strings.HasPrefix(path, p+".") {
+			return true
+		}
+	}
+	return false
+}
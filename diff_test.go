@@ -0,0 +1,105 @@
+package bson
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	old := D{
+		{"_id", int32(1)},
+		{"name", "john"},
+		{"age", int32(42)},
+		{"address", D{{"city", "Berlin"}, {"zip", "10115"}}},
+		{"tags", A{"a", "b", "c"}},
+		{"nick", "jj"},
+	}
+	new := D{
+		{"_id", int32(1)},
+		{"name", "john"},
+		{"age", int64(43)},
+		{"address", D{{"city", "Paris"}, {"zip", "10115"}, {"country", "FR"}}},
+		{"tags", A{"a", "x"}},
+		{"email", "john@example.com"},
+	}
+
+	have := Diff(old, new, nil)
+	want := []Change{
+		{Kind: ChangeModified, Path: "age", Index: -1, Old: int32(42), New: int64(43)},
+		{Kind: ChangeModified, Path: "address.city", Index: -1, Old: "Berlin", New: "Paris"},
+		{Kind: ChangeAdded, Path: "address.country", Index: -1, New: "FR"},
+		{Kind: ChangeModified, Path: "tags.1", Index: 1, Old: "b", New: "x"},
+		{Kind: ChangeRemoved, Path: "tags.2", Index: 2, Old: "c"},
+		{Kind: ChangeRemoved, Path: "nick", Index: -1, Old: "jj"},
+		{Kind: ChangeAdded, Path: "email", Index: -1, New: "john@example.com"},
+	}
+	mustDeepEqual(t, have, want)
+
+	mustEqual(t, len(Diff(old, old, nil)), 0)
+	mustEqual(t, ChangeAdded.String(), "added")
+	mustEqual(t, ChangeKind(42).String(), "ChangeKind(42)")
+}
+
+func TestDiffNumbers(t *testing.T) {
+	old := D{{"a", int32(1)}, {"b", A{int32(2)}}}
+	new := D{{"a", int64(1)}, {"b", A{2.0}}}
+
+	mustEqual(t, len(Diff(old, new, nil)), 0)
+
+	have := Diff(old, new, &DiffOptions{StrictNumbers: true})
+	want := []Change{
+		{Kind: ChangeModified, Path: "a", Index: -1, Old: int32(1), New: int64(1)},
+		{Kind: ChangeModified, Path: "b.0", Index: 0, Old: int32(2), New: 2.0},
+	}
+	mustDeepEqual(t, have, want)
+}
+
+func TestDiffUpdate(t *testing.T) {
+	testCases := []struct {
+		old, new D
+		want     D
+	}{
+		{
+			D{{"a", int32(1)}},
+			D{{"a", int32(1)}},
+			D{},
+		},
+		{
+			D{{"a", int32(1)}, {"b", D{{"c", "x"}, {"d", "y"}}}},
+			D{{"a", int32(2)}, {"b", D{{"c", "x"}}}, {"e", true}},
+			D{
+				{"$set", D{{"a", int32(2)}, {"e", true}}},
+				{"$unset", D{{"b.d", ""}}},
+			},
+		},
+		{
+			D{{"tags", A{"a", "b"}}},
+			D{{"tags", A{"a", "b", "c"}}},
+			D{{"$set", D{{"tags.2", "c"}}}},
+		},
+		{
+			D{{"tags", A{"a", "b", "c"}}, {"x", int32(1)}},
+			D{{"tags", A{"z", "b"}}},
+			D{
+				{"$set", D{{"tags", A{"z", "b"}}}},
+				{"$unset", D{{"x", ""}}},
+			},
+		},
+		{
+			D{{"items", A{D{{"tags", A{"a", "b"}}}, "x"}}},
+			D{{"items", A{D{{"tags", A{"a"}}}}}},
+			D{{"$set", D{{"items", A{D{{"tags", A{"a"}}}}}}}},
+		},
+		{
+			D{{"a", D{{"b", int32(1)}}}},
+			D{{"a", "scalar"}},
+			D{{"$set", D{{"a", "scalar"}}}},
+		},
+	}
+
+	for _, tc := range testCases {
+		update := DiffUpdate(tc.old, tc.new, nil)
+		mustDeepEqual(t, update, tc.want)
+
+		res, err := ApplyUpdate(tc.old, update)
+		mustOk(t, err)
+		mustDeepEqual(t, res, tc.new)
+	}
+}
@@ -0,0 +1,85 @@
+package bson
+
+// Get returns the value of the first element with the given key.
+func (d D) Get(key string) (any, bool) {
+	i := d.index(key)
+	if i == -1 {
+		return nil, false
+	}
+	return d[i].Value, true
+}
+
+// Has reports whether an element with the given key exists.
+func (d D) Has(key string) bool {
+	return d.index(key) != -1
+}
+
+// Keys returns keys of all elements in order.
+func (d D) Keys() []string {
+	keys := make([]string, len(d))
+	for i := range d {
+		keys[i] = d[i].Key
+	}
+	return keys
+}
+
+// Set replaces the value of the first element with the given key,
+// otherwise appends a new element to the end of the document.
+func (d *D) Set(key string, value any) {
+	if i := d.index(key); i != -1 {
+		(*d)[i].Value = value
+		return
+	}
+	*d = append(*d, E{Key: key, Value: value})
+}
+
+// Delete removes all elements with the given key preserving order of the rest.
+// Reports whether any element was removed.
+func (d *D) Delete(key string) bool {
+	doc := (*d)[:0]
+	for _, elem := range *d {
+		if elem.Key != key {
+			doc = append(doc, elem)
+		}
+	}
+
+	removed := len(doc) != len(*d)
+	// clear the tail to not hold references.
+	for i := len(doc); i < len(*d); i++ {
+		(*d)[i] = E{}
+	}
+	*d = doc
+	return removed
+}
+
+// Insert inserts a new element at the given position shifting the rest.
+// It panics if at is out of range [0, len(d)].
+func (d *D) Insert(at int, key string, value any) {
+	if at < 0 || at > len(*d) {
+		panic("bson: insert index out of range")
+	}
+
+	*d = append(*d, E{})
+	copy((*d)[at+1:], (*d)[at:])
+	(*d)[at] = E{Key: key, Value: value}
+}
+
+// Rename changes the key of the first element with the old key keeping its position.
+// Reports whether the element was found.
+func (d *D) Rename(oldKey, newKey string) bool {
+	i := d.index(oldKey)
+	if i == -1 {
+		return false
+	}
+	(*d)[i].Key = newKey
+	return true
+}
+
+func (d D) index(key string) int {
+	for i := range d {
+		if d[i].Key == key {
+			return i
+		}
+	}
+	return -1
+}
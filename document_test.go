@@ -0,0 +1,61 @@
+package bson
+
+import "testing"
+
+func TestDocument(t *testing.T) {
+	doc := D{{"a", int32(1)}, {"b", "two"}, {"c", true}}
+
+	t.Run("Get", func(t *testing.T) {
+		v, ok := doc.Get("b")
+		mustEqual(t, ok, true)
+		mustEqual(t, v.(string), "two")
+
+		_, ok = doc.Get("z")
+		mustEqual(t, ok, false)
+
+		mustEqual(t, doc.Has("c"), true)
+		mustEqual(t, doc.Has("z"), false)
+		mustDeepEqual(t, doc.Keys(), []string{"a", "b", "c"})
+	})
+
+	t.Run("Set", func(t *testing.T) {
+		d := append(D{}, doc...)
+		d.Set("b", "zwei")
+		d.Set("d", 4.0)
+		mustDeepEqual(t, d, D{{"a", int32(1)}, {"b", "zwei"}, {"c", true}, {"d", 4.0}})
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		d := D{{"a", 1}, {"b", 2}, {"a", 3}, {"c", 4}}
+		mustEqual(t, d.Delete("a"), true)
+		mustDeepEqual(t, d, D{{"b", 2}, {"c", 4}})
+		mustEqual(t, d.Delete("z"), false)
+		mustDeepEqual(t, d, D{{"b", 2}, {"c", 4}})
+	})
+
+	t.Run("Insert", func(t *testing.T) {
+		d := D{{"a", 1}, {"c", 3}}
+		d.Insert(1, "b", 2)
+		d.Insert(0, "_id", 0)
+		d.Insert(len(d), "z", 26)
+		mustDeepEqual(t, d.Keys(), []string{"_id", "a", "b", "c", "z"})
+
+		defer func() {
+			mustEqual(t, recover() != nil, true)
+		}()
+		d.Insert(10, "x", 1)
+	})
+
+	t.Run("Rename", func(t *testing.T) {
+		d := append(D{}, doc...)
+		mustEqual(t, d.Rename("b", "bb"), true)
+		mustEqual(t, d.Rename("z", "zz"), false)
+		mustDeepEqual(t, d, D{{"a", int32(1)}, {"bb", "two"}, {"c", true}})
+	})
+
+	t.Run("Exported", func(t *testing.T) {
+		d := D{E{Key: "x", Value: 1}}
+		mustEqual(t, d[0].Key, "x")
+		mustEqual(t, d[0].Value.(int), 1)
+	})
+}
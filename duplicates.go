@@ -0,0 +1,137 @@
+package bson
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// DuplicateKeyPolicy defines how the decoder handles repeated keys in a document.
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateAllow keeps the default behaviour: maps and structs get the last value,
+	// D gets all the elements.
+	DuplicateAllow DuplicateKeyPolicy = iota
+
+	// DuplicateError returns [ErrDuplicateKey].
+	DuplicateError
+
+	// DuplicateKeepFirst keeps the first value.
+	DuplicateKeepFirst
+
+	// DuplicateKeepLast keeps the last value, D keeps the position of the first element.
+	DuplicateKeepLast
+
+	// DuplicateCollect collects all the values into an array of the decoder [ArrayType].
+	// Only D, maps and struct fields of interface type can hold the collected values.
+	DuplicateCollect
+)
+
+// ErrDuplicateKey is returned by [DuplicateError] policy.
+var ErrDuplicateKey = errors.New("bson: duplicate key")
+
+// SetDuplicateKeyPolicy sets how repeated keys in decoded documents are handled.
+func (dec *Decoder) SetDuplicateKeyPolicy(p DuplicateKeyPolicy) {
+	dec.dups = p
+}
+
+// HasDuplicateKeys reports whether the document or any nested document has repeated keys.
+// Malformed documents and documents nested deeper than [DefaultMaxDepth] report false.
+func (r RawObject) HasDuplicateKeys() bool {
+	ok, err := hasDuplicateKeys(r, 1, false)
+	return ok && err == nil
+}
+
+// hasDuplicateKeys walks the document at the given nesting level starting from 1.
+func hasDuplicateKeys(data []byte, depth int, array bool) (bool, error) {
+	if depth > DefaultMaxDepth {
+		return false, depthError(DefaultMaxDepth)
+	}
+	iter, err := newReader(data)
+	if err != nil {
+		return false, err
+	}
+
+	var seen map[string]struct{}
+	if !array {
+		seen = map[string]struct{}{}
+	}
+	for iter.Next() {
+		typ, name, element := iter.Peek()
+		if !array {
+			key := trimlast(name)
+			if _, ok := seen[key]; ok {
+				return true, nil
+			}
+			seen[key] = struct{}{}
+		}
+
+		if typ == TypeDocument || typ == TypeArray {
+			ok, err := hasDuplicateKeys(element, depth+1, typ == TypeArray)
+			if ok || err != nil {
+				return ok, err
+			}
+		}
+	}
+	return false, iter.Err()
+}
+
+// newSeen returns a map to count keys of a document, nil when duplicates are allowed.
+func (ds *decodeState) newSeen() map[string]int {
+	if ds.dups == DuplicateAllow {
+		return nil
+	}
+	return map[string]int{}
+}
+
+// dup counts the key and reports whether the element must be skipped.
+func (ds *decodeState) dup(seen map[string]int, key string) (bool, error) {
+	if seen == nil {
+		return false, nil
+	}
+	n := seen[key]
+	seen[key] = n + 1
+	if n == 0 {
+		return false, nil
+	}
+
+	switch ds.dups {
+	case DuplicateError:
+		return false, fmt.Errorf("%w: %q", ErrDuplicateKey, key)
+	case DuplicateKeepFirst:
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// merge returns the value for the n-th occurrence of a key, old is the current value.
+func (ds *decodeState) merge(old, val any, n int) any {
+	switch {
+	case ds.dups != DuplicateCollect:
+		return val
+	case n == 2:
+		return ds.array([]any{old, val})
+	default:
+		return ds.appendArray(old, val)
+	}
+}
+
+// collectInto decodes the n-th occurrence of a key and collects it into v of interface type.
+func (ds *decodeState) collectInto(typ Type, element []byte, v reflect.Value, n int) error {
+	if v.Kind() != reflect.Interface {
+		return fmt.Errorf("%w: cannot collect duplicate key into %v", ErrTypeMismatch, v.Type())
+	}
+
+	val, err := ds.decodeValue(typ, element)
+	if err != nil {
+		return err
+	}
+	res := reflect.ValueOf(ds.merge(v.Interface(), val, n))
+	if !res.Type().AssignableTo(v.Type()) {
+		return fmt.Errorf("%w: cannot assign %v to %v", ErrTypeMismatch, res.Type(), v.Type())
+	}
+	v.Set(res)
+	return nil
+}
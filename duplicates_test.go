@@ -0,0 +1,162 @@
+package bson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDuplicateKeyPolicy(t *testing.T) {
+	raw := must(Marshal(D{
+		{"a", int32(1)},
+		{"b", int32(5)},
+		{"a", int32(2)},
+		{"a", int32(3)},
+	}))
+
+	type record struct {
+		A any   `bson:"a"`
+		B int32 `bson:"b"`
+	}
+	type typed struct {
+		A int32 `bson:"a"`
+	}
+
+	testCases := []struct {
+		policy DuplicateKeyPolicy
+		d      D
+		m      map[string]any
+		r      record
+		typed  typed
+	}{
+		{
+			policy: DuplicateAllow,
+			d:      D{{"a", int32(1)}, {"b", int32(5)}, {"a", int32(2)}, {"a", int32(3)}},
+			m:      map[string]any{"a": int32(3), "b": int32(5)},
+			r:      record{A: int32(3), B: 5},
+			typed:  typed{A: 3},
+		},
+		{
+			policy: DuplicateKeepFirst,
+			d:      D{{"a", int32(1)}, {"b", int32(5)}},
+			m:      map[string]any{"a": int32(1), "b": int32(5)},
+			r:      record{A: int32(1), B: 5},
+			typed:  typed{A: 1},
+		},
+		{
+			policy: DuplicateKeepLast,
+			d:      D{{"a", int32(3)}, {"b", int32(5)}},
+			m:      map[string]any{"a": int32(3), "b": int32(5)},
+			r:      record{A: int32(3), B: 5},
+			typed:  typed{A: 3},
+		},
+		{
+			policy: DuplicateCollect,
+			d:      D{{"a", []any{int32(1), int32(2), int32(3)}}, {"b", int32(5)}},
+			m:      map[string]any{"a": []any{int32(1), int32(2), int32(3)}, "b": int32(5)},
+			r:      record{A: []any{int32(1), int32(2), int32(3)}, B: 5},
+		},
+	}
+
+	for _, tc := range testCases {
+		decode := func(v any) error {
+			dec := NewDecodeBytes(raw)
+			dec.SetDuplicateKeyPolicy(tc.policy)
+			return dec.Decode(v)
+		}
+
+		var d D
+		mustOk(t, decode(&d))
+		mustDeepEqual(t, d, tc.d)
+
+		var m map[string]any
+		mustOk(t, decode(&m))
+		mustDeepEqual(t, m, tc.m)
+
+		var r record
+		mustOk(t, decode(&r))
+		mustDeepEqual(t, r, tc.r)
+
+		var ty typed
+		var tm map[string]int32
+		if tc.policy == DuplicateCollect {
+			mustEqual(t, errors.Is(decode(&ty), ErrTypeMismatch), true)
+			mustEqual(t, errors.Is(decode(&tm), ErrTypeMismatch), true)
+			continue
+		}
+		mustOk(t, decode(&ty))
+		mustEqual(t, ty, tc.typed)
+		mustOk(t, decode(&tm))
+		mustEqual(t, tm["a"], tc.typed.A)
+	}
+
+	// keys are counted per document.
+	dec := NewDecodeBytes(must(Marshal(D{{"a", D{{"a", int32(1)}}}, {"b", D{{"a", int32(2)}}}})))
+	dec.SetDuplicateKeyPolicy(DuplicateError)
+	var d D
+	mustOk(t, dec.Decode(&d))
+
+	dec = NewDecodeBytes(must(Marshal(D{{"x", D{{"y", int32(1)}, {"y", int32(2)}}}})))
+	dec.SetDuplicateKeyPolicy(DuplicateError)
+	err := dec.Decode(&d)
+	mustEqual(t, errors.Is(err, ErrDuplicateKey), true)
+
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("want DecodeError, got %v", err)
+	}
+	mustEqual(t, de.Path, "x.y")
+}
+
+func TestDuplicateKeepLastNested(t *testing.T) {
+	raw := must(Marshal(D{
+		{"a", D{{"x", int32(1)}}},
+		{"m", D{{"k", int32(1)}}},
+		{"a", D{{"y", int32(2)}}},
+		{"m", D{{"j", int32(2)}}},
+	}))
+
+	type inner struct {
+		X int32 `bson:"x"`
+		Y int32 `bson:"y"`
+	}
+	type record struct {
+		A inner            `bson:"a"`
+		M map[string]int32 `bson:"m"`
+	}
+
+	dec := NewDecodeBytes(raw)
+	dec.SetDuplicateKeyPolicy(DuplicateKeepLast)
+	var r record
+	mustOk(t, dec.Decode(&r))
+	mustDeepEqual(t, r, record{A: inner{Y: 2}, M: map[string]int32{"j": 2}})
+
+	dec = NewDecodeBytes(raw)
+	dec.SetDuplicateKeyPolicy(DuplicateKeepLast)
+	var m map[string]map[string]int32
+	mustOk(t, dec.Decode(&m))
+	mustDeepEqual(t, m, map[string]map[string]int32{"a": {"y": 2}, "m": {"j": 2}})
+}
+
+func TestHasDuplicateKeys(t *testing.T) {
+	mustEqual(t, RawObject(must(Marshal(D{{"a", int32(1)}, {"b", A{D{{"a", int32(1)}}, D{{"a", int32(2)}}}}}))).HasDuplicateKeys(), false)
+	mustEqual(t, RawObject(must(Marshal(D{{"a", int32(1)}, {"a", int32(1)}}))).HasDuplicateKeys(), true)
+	mustEqual(t, RawObject(must(Marshal(D{{"a", A{D{{"b", int32(1)}, {"b", int32(1)}}}}}))).HasDuplicateKeys(), true)
+	mustEqual(t, RawObject{1, 2, 3}.HasDuplicateKeys(), false)
+
+	// nested wraps the document into depth-1 documents without the encoder limits.
+	nested := func(doc []byte, depth int) RawObject {
+		for i := 1; i < depth; i++ {
+			n := len(doc) + 8
+			b := []byte{byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24), byte(TypeDocument), 'x', 0}
+			doc = append(append(b, doc...), 0)
+		}
+		return doc
+	}
+	dup := must(Marshal(D{{"a", int32(1)}, {"a", int32(2)}}))
+	mustEqual(t, nested(dup, DefaultMaxDepth).HasDuplicateKeys(), true)
+	mustEqual(t, nested(dup, DefaultMaxDepth+1).HasDuplicateKeys(), false)
+	mustEqual(t, nested(dup, 10_000).HasDuplicateKeys(), false)
+
+	_, err := hasDuplicateKeys(nested(dup, DefaultMaxDepth+1), 1, false)
+	mustEqual(t, errors.Is(err, ErrMaxDepthExceeded), true)
+}
@@ -0,0 +1,397 @@
+package bson
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/cristalhq/bson/bsonproto"
+)
+
+// appendExtJSON appends relaxed Extended JSON representation of v to b.
+// See https://www.mongodb.com/docs/manual/reference/mongodb-extended-json/
+func appendExtJSON(b []byte, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, "null"...), nil
+	case bool:
+		return strconv.AppendBool(b, v), nil
+	case string:
+		return appendJSONString(b, v), nil
+
+	case int:
+		return strconv.AppendInt(b, int64(v), 10), nil
+	case int8:
+		return strconv.AppendInt(b, int64(v), 10), nil
+	case int16:
+		return strconv.AppendInt(b, int64(v), 10), nil
+	case int32:
+		return strconv.AppendInt(b, int64(v), 10), nil
+	case int64:
+		return strconv.AppendInt(b, v, 10), nil
+	case uint:
+		return strconv.AppendUint(b, uint64(v), 10), nil
+	case uint8:
+		return strconv.AppendUint(b, uint64(v), 10), nil
+	case uint16:
+		return strconv.AppendUint(b, uint64(v), 10), nil
+	case uint32:
+		return strconv.AppendUint(b, uint64(v), 10), nil
+	case uint64:
+		return strconv.AppendUint(b, v, 10), nil
+	case float32:
+		return appendJSONFloat(b, float64(v)), nil
+	case float64:
+		return appendJSONFloat(b, v), nil
+
+	case ObjectID:
+		b = append(b, `{"$oid":"`...)
+		b = append(b, v.Hex()...)
+		return append(b, `"}`...), nil
+	case Timestamp:
+		b = append(b, `{"$timestamp":{"t":`...)
+		b = strconv.AppendUint(b, uint64(v.T()), 10)
+		b = append(b, `,"i":`...)
+		b = strconv.AppendUint(b, uint64(v.I()), 10)
+		return append(b, `}}`...), nil
+	case time.Time:
+		b = append(b, `{"$date":"`...)
+		b = v.UTC().AppendFormat(b, "2006-01-02T15:04:05.000Z07:00")
+		return append(b, `"}`...), nil
+	case []byte:
+		return appendExtJSONBinary(b, bsonproto.BinaryGeneric, v), nil
+	case bsonproto.Binary:
+		return appendExtJSONBinary(b, v.Subtype, v.B), nil
+	case bsonproto.Decimal128:
+		b = append(b, `{"$numberDecimal":"`...)
+		b = append(b, formatDecimal(v)...)
+		return append(b, `"}`...), nil
+	case Regex:
+		b = append(b, `{"$regularExpression":{"pattern":`...)
+		b = appendJSONString(b, v.Pattern)
+		b = append(b, `,"options":`...)
+		b = appendJSONString(b, v.Options)
+		return append(b, `}}`...), nil
+
+	case D:
+		return appendExtJSONDoc(b, v)
+	case M:
+		return appendExtJSONDoc(b, v.AsD())
+	case map[string]any:
+		return appendExtJSONDoc(b, M(v).AsD())
+	case A:
+		return appendExtJSONArray(b, v)
+	case []any:
+		return appendExtJSONArray(b, v)
+	case RawObject:
+		var d D
+		if err := Unmarshal(v, &d); err != nil {
+			return nil, err
+		}
+		return appendExtJSONDoc(b, d)
+
+	default:
+		return nil, fmt.Errorf("type %T is not supported by Extended JSON", v)
+	}
+}
+
+func appendExtJSONDoc(b []byte, d D) ([]byte, error) {
+	b = append(b, '{')
+	for i, elem := range d {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendJSONString(b, elem.Key)
+		b = append(b, ':')
+
+		var err error
+		b, err = appendExtJSON(b, elem.Value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return append(b, '}'), nil
+}
+
+func appendExtJSONArray(b []byte, a []any) ([]byte, error) {
+	b = append(b, '[')
+	for i, v := range a {
+		if i > 0 {
+			b = append(b, ',')
+		}
+
+		var err error
+		b, err = appendExtJSON(b, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return append(b, ']'), nil
+}
+
+func appendExtJSONBinary(b []byte, subtype bsonproto.BinarySubtype, data []byte) []byte {
+	b = append(b, `{"$binary":{"base64":"`...)
+	b = append(b, base64.StdEncoding.EncodeToString(data)...)
+	b = append(b, `","subType":"`...)
+	b = append(b, hex.EncodeToString([]byte{byte(subtype)})...)
+	return append(b, `"}}`...)
+}
+
+func appendJSONString(b []byte, s string) []byte {
+	// json.Marshal on a string cannot fail.
+	return append(b, must(json.Marshal(s))...)
+}
+
+func appendJSONFloat(b []byte, f float64) []byte {
+	switch {
+	case math.IsNaN(f):
+		return append(b, `{"$numberDouble":"NaN"}`...)
+	case math.IsInf(f, 1):
+		return append(b, `{"$numberDouble":"Infinity"}`...)
+	case math.IsInf(f, -1):
+		return append(b, `{"$numberDouble":"-Infinity"}`...)
+	}
+
+	start := len(b)
+	b = strconv.AppendFloat(b, f, 'g', -1, 64)
+	// keep the value a double when it's read back.
+	if bytes.IndexAny(b[start:], ".e") == -1 {
+		b = append(b, ".0"...)
+	}
+	return b
+}
+
+// parseExtJSON parses relaxed or canonical Extended JSON document.
+func parseExtJSON(data []byte) (D, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok != json.Delim('{') {
+		return nil, errors.New("Extended JSON: expected document")
+	}
+
+	d, err := parseExtJSONDoc(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("Extended JSON: unexpected data after document")
+	}
+	return d, nil
+}
+
+func parseExtJSONValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		switch tok {
+		case '{':
+			d, err := parseExtJSONDoc(dec)
+			if err != nil {
+				return nil, err
+			}
+			return extJSONWrapper(d)
+		case '[':
+			a := A{}
+			for dec.More() {
+				v, err := parseExtJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				a = append(a, v)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return a, nil
+		default:
+			return nil, fmt.Errorf("Extended JSON: unexpected %v", tok)
+		}
+	case json.Number:
+		return parseJSONNumber(tok)
+	default:
+		// string, bool or nil.
+		return tok, nil
+	}
+}
+
+func parseExtJSONDoc(dec *json.Decoder) (D, error) {
+	d := D{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, fmt.Errorf("Extended JSON: expected key, got %v", tok)
+		}
+
+		v, err := parseExtJSONValue(dec)
+		if err != nil {
+			return nil, err
+		}
+		d = append(d, E{Key: key, Value: v})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func parseJSONNumber(n json.Number) (any, error) {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		if i >= math.MinInt32 && i <= math.MaxInt32 {
+			return int32(i), nil
+		}
+		return i, nil
+	}
+	return strconv.ParseFloat(string(n), 64)
+}
+
+// extJSONWrapper converts a type wrapper like {"$oid": "..."} to the value.
+// Documents that are not wrappers are returned as is.
+func extJSONWrapper(d D) (any, error) {
+	if len(d) != 1 {
+		return d, nil
+	}
+
+	switch key, val := d[0].Key, d[0].Value; key {
+	case "$oid":
+		s, ok := val.(string)
+		if !ok {
+			return nil, errors.New("Extended JSON: $oid must be a string")
+		}
+		return ObjectIDFromHex(s)
+
+	case "$numberInt", "$numberLong", "$numberDouble":
+		s, ok := val.(string)
+		if !ok {
+			return nil, fmt.Errorf("Extended JSON: %s must be a string", key)
+		}
+		switch key {
+		case "$numberInt":
+			i, err := strconv.ParseInt(s, 10, 32)
+			return int32(i), err
+		case "$numberLong":
+			return strconv.ParseInt(s, 10, 64)
+		default:
+			return strconv.ParseFloat(s, 64)
+		}
+
+	case "$numberDecimal":
+		s, ok := val.(string)
+		if !ok {
+			return nil, errors.New("Extended JSON: $numberDecimal must be a string")
+		}
+		return parseDecimal(s)
+
+	case "$binary":
+		inner, ok := val.(D)
+		if !ok {
+			return nil, errors.New("Extended JSON: $binary must be a document")
+		}
+		var bin bsonproto.Binary
+		for _, elem := range inner {
+			s, ok := elem.Value.(string)
+			if !ok {
+				return nil, errors.New("Extended JSON: invalid $binary")
+			}
+			switch elem.Key {
+			case "base64":
+				b, err := base64.StdEncoding.DecodeString(s)
+				if err != nil {
+					return nil, fmt.Errorf("Extended JSON: invalid $binary: %w", err)
+				}
+				if len(b) > 0 {
+					bin.B = b
+				}
+			case "subType":
+				t, err := hex.DecodeString(s)
+				if err != nil || len(t) != 1 {
+					return nil, errors.New("Extended JSON: invalid $binary subType")
+				}
+				bin.Subtype = bsonproto.BinarySubtype(t[0])
+			}
+		}
+		return bin, nil
+
+	case "$date":
+		switch val := val.(type) {
+		case string:
+			return time.Parse(time.RFC3339, val)
+		case int32:
+			return time.UnixMilli(int64(val)).UTC(), nil
+		case int64:
+			return time.UnixMilli(val).UTC(), nil
+		default:
+			return nil, errors.New("Extended JSON: invalid $date")
+		}
+
+	case "$timestamp":
+		inner, ok := val.(D)
+		if !ok {
+			return nil, errors.New("Extended JSON: $timestamp must be a document")
+		}
+		var t, i int64
+		for _, elem := range inner {
+			n, ok := asInt64(elem.Value)
+			if !ok {
+				return nil, errors.New("Extended JSON: invalid $timestamp")
+			}
+			switch elem.Key {
+			case "t":
+				t = n
+			case "i":
+				i = n
+			}
+		}
+		return NewTimestampWithCounter(time.Unix(t, 0), uint32(i)), nil
+
+	case "$regularExpression":
+		inner, ok := val.(D)
+		if !ok {
+			return nil, errors.New("Extended JSON: $regularExpression must be a document")
+		}
+		var re Regex
+		for _, elem := range inner {
+			s, _ := elem.Value.(string)
+			switch elem.Key {
+			case "pattern":
+				re.Pattern = s
+			case "options":
+				re.Options = s
+			}
+		}
+		return re, nil
+
+	default:
+		return d, nil
+	}
+}
+
+func asInt64(v any) (int64, bool) {
+	switch v := v.(type) {
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
@@ -7,12 +7,21 @@ import (
 	"reflect"
 	"sort"
 	"strconv"
+	"time"
+
+	"github.com/cristalhq/bson/bsonproto"
 )
 
 // Encoder writes BSON values to an output stream.
 type Encoder struct {
-	w   io.Writer
-	buf []byte
+	w        io.Writer
+	buf      []byte
+	depth    int
+	seen     map[refKey]struct{} // maps, slices and pointers being encoded.
+	maxDepth int
+	maxSize  int
+	utf8     UTF8Mode
+	keyRules *KeyRules
 }
 
 // NewEncoder returns a new encoder that writes to w.
@@ -34,6 +43,20 @@ func (enc *Encoder) Encode(v any) error {
 }
 
 func (enc *Encoder) marshal(v any) error {
+	start := len(enc.buf)
+	if err := enc.marshalValue(v); err != nil {
+		return err
+	}
+	if err := enc.checkSize(len(enc.buf) - start); err != nil {
+		return err
+	}
+	if enc.keyRules != nil {
+		return validateKeys(enc.buf[start:], enc.keyRules, "", 1, limit(enc.maxDepth, DefaultMaxDepth), false)
+	}
+	return nil
+}
+
+func (enc *Encoder) marshalValue(v any) error {
 	var err error
 	switch v := v.(type) {
 	case D:
@@ -53,6 +76,11 @@ func (enc *Encoder) marshal(v any) error {
 
 	default:
 		switch rv := reflect.ValueOf(v); rv.Kind() {
+		case reflect.Ptr:
+			if rv.IsNil() {
+				return fmt.Errorf("nil %T is not supported", v)
+			}
+			return enc.marshalValue(rv.Elem().Interface())
 		case reflect.Struct:
 			_, err = enc.writeStruct(rv)
 		case reflect.Map:
@@ -67,14 +95,19 @@ func (enc *Encoder) marshal(v any) error {
 }
 
 func (enc *Encoder) writeD(d D) (int, error) {
+	if err := enc.enter(); err != nil {
+		return 0, err
+	}
+	defer enc.leave()
+
 	start := len(enc.buf)
 	enc.buf = append(enc.buf, 0, 0, 0, 0)
 	count := 4 + 1 // sizeof(int) + sizeof(\0)
 
 	for i := 0; i < len(d); i++ {
-		n, err := enc.writeAny(d[i].K, d[i].V)
+		n, err := enc.writeAny(d[i].Key, d[i].Value)
 		if err != nil {
-			return 0, err
+			return 0, withKey(err, d[i].Key)
 		}
 		count += n
 	}
@@ -88,6 +121,11 @@ func (enc *Encoder) writeD(d D) (int, error) {
 }
 
 func (enc *Encoder) writeA(a A) (int, error) {
+	if err := enc.enter(); err != nil {
+		return 0, err
+	}
+	defer enc.leave()
+
 	start := len(enc.buf)
 	enc.buf = append(enc.buf, 0, 0, 0, 0)
 	count := 4 + 1 // sizeof(int) + sizeof(\0)
@@ -95,7 +133,7 @@ func (enc *Encoder) writeA(a A) (int, error) {
 	for i := range a {
 		n, err := enc.writeAny(strconv.Itoa(i), a[i])
 		if err != nil {
-			return 0, err
+			return 0, withKey(err, strconv.Itoa(i))
 		}
 		count += n
 	}
@@ -116,9 +154,9 @@ func (enc *Encoder) writeMap(v reflect.Value) (int, error) {
 	d := make(D, v.Len())
 
 	for i, key := range v.MapKeys() {
-		d[i] = e{
-			K: key.String(),
-			V: v.MapIndex(key).Interface(),
+		d[i] = E{
+			Key:   key.String(),
+			Value: v.MapIndex(key).Interface(),
 		}
 	}
 	sort.Sort(d)
@@ -126,16 +164,25 @@ func (enc *Encoder) writeMap(v reflect.Value) (int, error) {
 }
 
 func (enc *Encoder) writeStruct(v reflect.Value) (int, error) {
+	if err := enc.enter(); err != nil {
+		return 0, err
+	}
+	defer enc.leave()
+
 	start := len(enc.buf)
 	enc.buf = append(enc.buf, 0, 0, 0, 0)
 	count := 4 + 1 // sizeof(int) + sizeof(\0)
 
-	d := getStruct(v).asDoc(v)
+	info, err := getStruct(v)
+	if err != nil {
+		return 0, err
+	}
+	d := info.asDoc(v)
 
 	for i := 0; i < len(d); i++ {
 		n, err := enc.writeAny(d[i].Key, d[i].Val)
 		if err != nil {
-			return 0, err
+			return 0, withKey(err, d[i].Key)
 		}
 		count += n
 	}
@@ -153,6 +200,11 @@ func (enc *Encoder) writeSlice(v reflect.Value) (int, error) {
 		return enc.writeA(a)
 	}
 
+	if err := enc.enter(); err != nil {
+		return 0, err
+	}
+	defer enc.leave()
+
 	start := len(enc.buf)
 	enc.buf = append(enc.buf, 0, 0, 0, 0)
 	count := 4 + 1 // sizeof(int) + sizeof(\0)
@@ -161,7 +213,7 @@ func (enc *Encoder) writeSlice(v reflect.Value) (int, error) {
 	for i := 0; i < n; i++ {
 		n, err := enc.writeAny(strconv.Itoa(i), v.Index(i).Interface())
 		if err != nil {
-			return 0, err
+			return 0, withKey(err, strconv.Itoa(i))
 		}
 		count += n
 	}
@@ -175,12 +227,23 @@ func (enc *Encoder) writeSlice(v reflect.Value) (int, error) {
 }
 
 func (enc *Encoder) writeAny(ename string, v any) (int, error) {
+	ename, err := enc.elemName(ename)
+	if err != nil {
+		return 0, err
+	}
+
 	var count int
 
 	switch v := v.(type) {
+	case nil:
+		count += enc.writeElem(TypeNull, ename)
 	case string:
+		s, err := checkUTF8(enc.utf8, v)
+		if err != nil {
+			return 0, err
+		}
 		count += enc.writeElem(TypeString, ename)
-		count += enc.writeString(v)
+		count += enc.writeString(s)
 	case bool:
 		count += enc.writeElem(TypeBool, ename)
 		count += enc.writeBool(v)
@@ -228,6 +291,90 @@ func (enc *Encoder) writeAny(ename string, v any) (int, error) {
 		count += enc.writeElem(TypeDouble, ename)
 		count += enc.writeInt64(int64(math.Float64bits(float64(v))))
 
+	case Timestamp:
+		count += enc.writeElem(TypeTimestamp, ename)
+		count += enc.writeInt64(int64(v))
+	case ObjectID:
+		count += enc.writeElem(TypeObjectID, ename)
+		enc.buf = append(enc.buf, v[:]...)
+		count += len(v)
+	case time.Time:
+		count += enc.writeElem(TypeDateTime, ename)
+		count += enc.writeInt64(v.UnixMilli())
+	case []byte:
+		count += enc.writeElem(TypeBinary, ename)
+		count += enc.writeBinary(bsonproto.BinaryGeneric, v)
+	case bsonproto.Binary:
+		count += enc.writeElem(TypeBinary, ename)
+		count += enc.writeBinary(v.Subtype, v.B)
+	case Regex:
+		count += enc.writeElem(TypeRegex, ename)
+		enc.buf = append(enc.buf, v.Pattern...)
+		enc.buf = append(enc.buf, 0)
+		enc.buf = append(enc.buf, v.Options...)
+		enc.buf = append(enc.buf, 0)
+		count += len(v.Pattern) + 1 + len(v.Options) + 1
+	case bsonproto.Decimal128:
+		count += enc.writeElem(TypeDecimal, ename)
+		count += enc.writeInt64(int64(v.L))
+		count += enc.writeInt64(int64(v.H))
+	case MinKey:
+		count += enc.writeElem(TypeMinKey, ename)
+	case MaxKey:
+		count += enc.writeElem(TypeMaxKey, ename)
+
+	case D:
+		key, err := enc.enterRef(reflect.ValueOf(v))
+		if err != nil {
+			return 0, err
+		}
+		defer enc.leaveRef(key)
+
+		count += enc.writeElem(TypeDocument, ename)
+		n, err := enc.writeD(v)
+		if err != nil {
+			return 0, err
+		}
+		count += n
+	case M:
+		key, err := enc.enterRef(reflect.ValueOf(v))
+		if err != nil {
+			return 0, err
+		}
+		defer enc.leaveRef(key)
+
+		count += enc.writeElem(TypeDocument, ename)
+		n, err := enc.writeD(v.AsD())
+		if err != nil {
+			return 0, err
+		}
+		count += n
+	case A:
+		key, err := enc.enterRef(reflect.ValueOf(v))
+		if err != nil {
+			return 0, err
+		}
+		defer enc.leaveRef(key)
+
+		count += enc.writeElem(TypeArray, ename)
+		n, err := enc.writeA(v)
+		if err != nil {
+			return 0, err
+		}
+		count += n
+	case RawObject:
+		count += enc.writeElem(TypeDocument, ename)
+		enc.buf = append(enc.buf, v...)
+		count += len(v)
+	case RawArray:
+		count += enc.writeElem(TypeArray, ename)
+		enc.buf = append(enc.buf, v...)
+		count += len(v)
+	case RawValue:
+		count += enc.writeElem(v.Type, ename)
+		enc.buf = append(enc.buf, v.Data...)
+		count += len(v.Data)
+
 	default:
 		return enc.writeValue(ename, reflect.ValueOf(v))
 	}
@@ -241,8 +388,25 @@ func (enc *Encoder) writeValue(ename string, v reflect.Value) (int, error) {
 
 	var count int
 	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return enc.writeElem(TypeNull, ename), nil
+		}
+		key, err := enc.enterRef(v)
+		if err != nil {
+			return 0, err
+		}
+		defer enc.leaveRef(key)
+
+		return enc.writeAny(ename, v.Elem().Interface())
 
 	case reflect.Map:
+		key, err := enc.enterRef(v)
+		if err != nil {
+			return 0, err
+		}
+		defer enc.leaveRef(key)
+
 		count += enc.writeElem(TypeDocument, ename)
 		n, err := enc.writeMap(v)
 		if err != nil {
@@ -259,6 +423,12 @@ func (enc *Encoder) writeValue(ename string, v reflect.Value) (int, error) {
 		count += n
 
 	case reflect.Array, reflect.Slice:
+		key, err := enc.enterRef(v)
+		if err != nil {
+			return 0, err
+		}
+		defer enc.leaveRef(key)
+
 		count += enc.writeElem(TypeArray, ename)
 		n, err := enc.writeSlice(v)
 		if err != nil {
@@ -272,6 +442,43 @@ func (enc *Encoder) writeValue(ename string, v reflect.Value) (int, error) {
 	return count, nil
 }
 
+// refKey identifies a map, slice or pointer being encoded.
+type refKey struct {
+	ptr uintptr
+	len int
+	typ reflect.Type
+}
+
+// enterRef marks the value as being encoded and reports a cycle
+// if the value is already being encoded up the stack.
+func (enc *Encoder) enterRef(v reflect.Value) (refKey, error) {
+	if v.Kind() == reflect.Array {
+		return refKey{}, nil
+	}
+	key := refKey{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	if key.ptr == 0 {
+		return refKey{}, nil
+	}
+
+	if _, ok := enc.seen[key]; ok {
+		return refKey{}, &UnsupportedValueError{Type: v.Type(), Reason: "encountered a cycle"}
+	}
+	if enc.seen == nil {
+		enc.seen = map[refKey]struct{}{}
+	}
+	enc.seen[key] = struct{}{}
+	return key, nil
+}
+
+func (enc *Encoder) leaveRef(key refKey) {
+	if key.ptr != 0 {
+		delete(enc.seen, key)
+	}
+}
+
 func (enc *Encoder) writeElem(typ Type, key string) int {
 	enc.buf = append(enc.buf, byte(typ))
 	enc.buf = append(enc.buf, key...)
@@ -287,6 +494,13 @@ func (enc *Encoder) writeString(s string) int {
 	return 4 + size
 }
 
+func (enc *Encoder) writeBinary(subtype bsonproto.BinarySubtype, b []byte) int {
+	enc.writeInt32(int32(len(b)))
+	enc.buf = append(enc.buf, byte(subtype))
+	enc.buf = append(enc.buf, b...)
+	return 4 + 1 + len(b)
+}
+
 func (enc *Encoder) writeInt32(v int32) int {
 	enc.buf = append(enc.buf,
 		byte(v),
@@ -2,7 +2,12 @@ package bson
 
 import (
 	"bytes"
+	"errors"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/cristalhq/bson/bsonproto"
 )
 
 func TestEncodeA(t *testing.T) {
@@ -79,6 +84,21 @@ func TestEncodeD(t *testing.T) {
 			doc:  D{{"a", int32(10)}, {"c", true}, {"b", int64(10203040)}},
 			want: "1b0000001061000a00000008630001126200a0af9b000000000000",
 		},
+
+		{
+			doc:  D{{"ts", Timestamp(1<<32 | 2)}},
+			want: "1100000011747300020000000100000000",
+		},
+
+		{
+			doc:  D{{"d", D{{"x", true}}}},
+			want: "1100000003640009000000087800010000",
+		},
+
+		{
+			doc:  D{{"a", A{int32(1)}}, {"o", ObjectID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}}},
+			want: "230000000461000c0000001030000100000000076f000102030405060708090a0b0c00",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -90,6 +110,63 @@ func TestEncodeD(t *testing.T) {
 	}
 }
 
+func TestEncodeNested(t *testing.T) {
+	inner := D{{"x", true}, {"y", int32(1)}}
+	want := must(Marshal(D{{"v", inner}, {"a", A{int32(1), "s"}}}))
+
+	got := must(Marshal(D{{"v", M{"x": true, "y": int32(1)}}, {"a", A{int32(1), "s"}}}))
+	mustDeepEqual(t, got, want)
+
+	got = must(Marshal(D{{"v", RawObject(must(Marshal(inner)))}, {"a", RawArray(must(Marshal(A{int32(1), "s"})))}}))
+	mustDeepEqual(t, got, want)
+}
+
+func TestEncodeValues(t *testing.T) {
+	testCases := []struct {
+		doc  D
+		want string
+	}{
+		{
+			doc:  D{{"n", nil}},
+			want: "080000000a6e0000",
+		},
+		{
+			doc:  D{{"t", time.UnixMilli(1)}},
+			want: "10000000097400010000000000000000",
+		},
+		{
+			doc:  D{{"b", []byte{1, 2}}},
+			want: "0f0000000562000200000000010200",
+		},
+		{
+			doc:  D{{"b", bsonproto.Binary{Subtype: bsonproto.BinaryUUID, B: []byte{1}}}},
+			want: "0e00000005620001000000040100",
+		},
+		{
+			doc:  D{{"r", Regex{Pattern: "a", Options: "i"}}},
+			want: "0c0000000b72006100690000",
+		},
+		{
+			doc:  D{{"d", bsonproto.Decimal128{L: 1}}},
+			want: "180000001364000100000000000000000000000000000000",
+		},
+		{
+			doc:  D{{"m", MinKey{}}},
+			want: "08000000ff6d0000",
+		},
+		{
+			doc:  D{{"m", MaxKey{}}},
+			want: "080000007f6d0000",
+		},
+	}
+
+	for _, tc := range testCases {
+		got, err := Marshal(tc.doc)
+		mustOk(t, err)
+		wantBytes(t, got, tc.want)
+	}
+}
+
 func TestEncodeM(t *testing.T) {
 	testCases := []struct {
 		doc  M
@@ -243,3 +320,59 @@ func TestEncodeReflectArray(t *testing.T) {
 		wantBytes(t, buf.Bytes(), tc.want)
 	}
 }
+
+func TestEncodePointers(t *testing.T) {
+	type inner struct {
+		X int32 `bson:"x"`
+	}
+	type outer struct {
+		In  *inner `bson:"in"`
+		Nil *inner `bson:"nil"`
+	}
+
+	in := &inner{X: 1}
+	got, err := Marshal(&outer{In: in})
+	mustOk(t, err)
+	mustDeepEqual(t, got, must(Marshal(D{{"in", D{{"x", int32(1)}}}, {"nil", nil}})))
+
+	// the same pointer twice is not a cycle.
+	_, err = Marshal(D{{"a", in}, {"b", in}, {"c", A{in, in}}})
+	mustOk(t, err)
+}
+
+func TestEncodeCycle(t *testing.T) {
+	type node struct {
+		Name string `bson:"name"`
+		Next *node  `bson:"next"`
+	}
+
+	a := &node{Name: "a"}
+	a.Next = &node{Name: "b", Next: a}
+
+	var uerr *UnsupportedValueError
+	_, err := Marshal(D{{"list", A{a}}})
+	if !errors.As(err, &uerr) {
+		t.Fatalf("want UnsupportedValueError, got %v", err)
+	}
+	mustEqual(t, uerr.Path, "list.0.next.next")
+	mustEqual(t, uerr.Type == reflect.TypeOf(a), true)
+	mustEqual(t, uerr.Error(), "bson: unsupported value of type *bson.node at list.0.next.next: encountered a cycle")
+
+	m := M{"a": int32(1)}
+	m["self"] = m
+	_, err = Marshal(m)
+	if !errors.As(err, &uerr) {
+		t.Fatalf("want UnsupportedValueError, got %v", err)
+	}
+	mustEqual(t, uerr.Path, "self.self")
+
+	mm := map[string]any{}
+	mm["x"] = A{mm}
+	_, err = Marshal(mm)
+	mustEqual(t, errors.As(err, &uerr), true)
+
+	s := make([]any, 1)
+	s[0] = s
+	_, err = Marshal(D{{"s", s}})
+	mustEqual(t, errors.As(err, &uerr), true)
+}
@@ -0,0 +1,197 @@
+package bson
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/cristalhq/bson/bsonproto"
+)
+
+// EqualOptions configures [Equal].
+type EqualOptions struct {
+	// IgnoreOrder compares documents as sets of fields, by default order of fields matters.
+	IgnoreOrder bool
+
+	// StrictNumbers treats numbers of different types as different values,
+	// by default int32(1), int64(1) and 1.0 are equal as in MongoDB.
+	StrictNumbers bool
+}
+
+// Equal reports whether a and b are equal BSON values.
+//
+// Unlike [reflect.DeepEqual] it compares numbers by value, treats NaN as equal to NaN,
+// and compares D, M, structs and RawObject as documents.
+//
+// opts can be nil. Values which cannot be represented in BSON, like channels or malformed raw documents,
+// are not equal to anything.
+func Equal(a, b any, opts *EqualOptions) bool {
+	if opts == nil {
+		opts = &EqualOptions{}
+	}
+	return equal(a, b, opts)
+}
+
+func equal(a, b any, opts *EqualOptions) bool {
+	if x, ok := asDocument(a); ok {
+		y, ok := asDocument(b)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i, elem := range x {
+			if !opts.IgnoreOrder {
+				if elem.Key != y[i].Key || !equal(elem.Value, y[i].Value, opts) {
+					return false
+				}
+				continue
+			}
+			v, ok := y.Get(elem.Key)
+			if !ok || !equal(elem.Value, v, opts) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if x, ok := asArray(a); ok {
+		y, ok := asArray(b)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i], opts) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return equalValues(a, b, opts.StrictNumbers)
+}
+
+// Hash returns a hash of the BSON value consistent with [Equal] for any options:
+// equal values have equal hashes.
+// The hash is stable across processes and can be used as a cache key.
+func Hash(v any) uint64 {
+	h := fnv.New64a()
+	hashValue(h, v)
+	return h.Sum64()
+}
+
+func hashValue(h hash.Hash64, v any) {
+	order, val := canonicalOrder(v)
+	h.Write([]byte{byte(order)})
+
+	switch order {
+	case orderMinKey, orderUndefined, orderNull, orderMaxKey, orderInvalid:
+	case orderNumber:
+		hashNumber(h, val)
+	case orderString:
+		hashString(h, val.(string))
+
+	case orderDocument:
+		// fields are hashed independently and summed, so the order doesn't matter.
+		d := val.(D)
+		var sum uint64
+		for _, elem := range d {
+			fh := fnv.New64a()
+			hashString(fh, elem.Key)
+			hashValue(fh, elem.Value)
+			sum += fh.Sum64()
+		}
+		hashUint64(h, uint64(len(d)))
+		hashUint64(h, sum)
+
+	case orderArray:
+		a := val.(A)
+		hashUint64(h, uint64(len(a)))
+		for _, elem := range a {
+			hashValue(h, elem)
+		}
+
+	case orderBinary:
+		b := val.(bsonproto.Binary)
+		h.Write([]byte{byte(b.Subtype)})
+		h.Write(b.B)
+	case orderObjectID:
+		oid := val.(ObjectID)
+		h.Write(oid[:])
+	case orderBool:
+		if val.(bool) {
+			h.Write([]byte{1})
+		} else {
+			h.Write([]byte{0})
+		}
+	case orderDate:
+		t := val.(time.Time)
+		hashUint64(h, uint64(t.Unix()))
+		hashUint64(h, uint64(t.Nanosecond()))
+	case orderTimestamp:
+		hashUint64(h, uint64(val.(Timestamp)))
+	case orderRegex:
+		re := val.(Regex)
+		hashString(h, re.Pattern)
+		hashString(h, re.Options)
+	case orderDBPointer, orderCode, orderCodeWithScope:
+		h.Write(val.(RawValue).Data)
+	}
+}
+
+// hashNumber hashes numbers by value: integers as int64, other exact floats as float64.
+func hashNumber(h hash.Hash64, v any) {
+	switch v := v.(type) {
+	case int64:
+		hashInt(h, v)
+		return
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			hashInt(h, int64(v))
+			return
+		}
+		if !math.IsNaN(v) {
+			hashFloat(h, v)
+			return
+		}
+	}
+
+	f, nan := bigNumber(v)
+	if nan {
+		h.Write([]byte{'n'})
+		return
+	}
+	if f.IsInt() {
+		if n, acc := f.Int64(); acc == big.Exact {
+			hashInt(h, n)
+			return
+		}
+	}
+	if x, acc := f.Float64(); acc == big.Exact {
+		hashFloat(h, x)
+		return
+	}
+	hashString(h, f.Text('p', 0))
+}
+
+func hashInt(h hash.Hash64, n int64) {
+	h.Write([]byte{'i'})
+	hashUint64(h, uint64(n))
+}
+
+func hashFloat(h hash.Hash64, f float64) {
+	h.Write([]byte{'f'})
+	hashUint64(h, math.Float64bits(f))
+}
+
+func hashString(h hash.Hash64, s string) {
+	hashUint64(h, uint64(len(s)))
+	h.Write([]byte(s))
+}
+
+func hashUint64(h hash.Hash64, n uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], n)
+	h.Write(b[:])
+}
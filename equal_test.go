@@ -0,0 +1,98 @@
+package bson
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cristalhq/bson/bsonproto"
+)
+
+func TestEqual(t *testing.T) {
+	ordered := &EqualOptions{}
+	unordered := &EqualOptions{IgnoreOrder: true}
+	strict := &EqualOptions{StrictNumbers: true}
+
+	oid := NewObjectID()
+	now := time.Now()
+
+	testCases := []struct {
+		a, b any
+		opts *EqualOptions
+		want bool
+	}{
+		{int32(1), int64(1), nil, true},
+		{int32(1), 1.0, nil, true},
+		{int32(1), decimal(10, -1), nil, true},
+		{int32(1), int64(1), strict, false},
+		{int32(1), int32(1), strict, true},
+		{math.NaN(), math.NaN(), nil, true},
+		{0.0, math.Copysign(0, -1), nil, true},
+		{"a", "a", nil, true},
+		{"a", "b", nil, false},
+		{nil, nil, nil, true},
+		{nil, int32(0), nil, false},
+		{oid, oid, nil, true},
+		{now, now.UTC(), nil, true},
+		{[]byte{1}, bsonproto.Binary{B: []byte{1}}, nil, true},
+
+		{D{{"a", int32(1)}, {"b", "x"}}, D{{"a", int32(1)}, {"b", "x"}}, nil, true},
+		{D{{"a", int32(1)}, {"b", "x"}}, D{{"b", "x"}, {"a", int32(1)}}, ordered, false},
+		{D{{"a", int32(1)}, {"b", "x"}}, D{{"b", "x"}, {"a", int32(1)}}, unordered, true},
+		{D{{"a", D{{"x", 1}, {"y", 2}}}}, D{{"a", D{{"y", 2}, {"x", 1}}}}, unordered, true},
+		{D{{"a", int32(1)}}, D{{"a", int32(1)}, {"b", "x"}}, unordered, false},
+		{D{{"a", int32(1)}}, M{"a": int64(1)}, nil, true},
+		{D{{"a", int32(1)}}, M{"a": int64(1)}, strict, false},
+		{D{{"a", A{int32(1)}}}, RawObject(must(Marshal(D{{"a", A{int32(1)}}}))), strict, true},
+
+		{A{int32(1), "x"}, []any{1.0, "x"}, nil, true},
+		{A{int32(1), "x"}, A{"x", int32(1)}, unordered, false},
+		{A{int32(1)}, A{int32(1), int32(1)}, nil, false},
+		{A{}, D{}, nil, false},
+	}
+
+	for _, tc := range testCases {
+		have := Equal(tc.a, tc.b, tc.opts)
+		if have != tc.want {
+			t.Fatalf("Equal(%v, %v) = %v, want %v", tc.a, tc.b, have, tc.want)
+		}
+		if have && Hash(tc.a) != Hash(tc.b) {
+			t.Fatalf("Hash(%v) != Hash(%v)", tc.a, tc.b)
+		}
+	}
+}
+
+func TestHash(t *testing.T) {
+	equal := [][]any{
+		{int32(1), int64(1), 1.0, uint64(1), decimal(1, 0), decimal(100, -2)},
+		{0.5, decimal(5, -1)},
+		{math.Inf(1), bsonproto.Decimal128{H: 0x7800000000000000}},
+		{math.NaN(), -math.NaN()},
+		{float64(1 << 63), uint64(1 << 63)},
+		{D{{"a", int32(1)}, {"b", "x"}}, D{{"b", "x"}, {"a", 1.0}}, M{"a": int64(1), "b": "x"}},
+		{A{D{{"x", int32(1)}}}, []any{map[string]any{"x": 1}}},
+	}
+
+	for _, values := range equal {
+		for _, v := range values[1:] {
+			if Hash(values[0]) != Hash(v) {
+				t.Fatalf("Hash(%v) != Hash(%v)", values[0], v)
+			}
+		}
+	}
+
+	different := []any{
+		nil, MinKey{}, MaxKey{}, int32(0), int32(1), 0.1, "", "a", "b",
+		D{}, D{{"a", nil}}, D{{"b", nil}}, A{}, A{nil}, A{"a", "b"}, A{"b", "a"},
+		[]byte{}, []byte{0}, true, false, NilObjectID, time.Unix(0, 0),
+		Timestamp(0), Regex{Pattern: "a"}, Regex{Pattern: "a", Options: "i"},
+	}
+	seen := map[uint64]any{}
+	for _, v := range different {
+		h := Hash(v)
+		if prev, ok := seen[h]; ok {
+			t.Fatalf("Hash(%v) == Hash(%v)", v, prev)
+		}
+		seen[h] = v
+	}
+}
@@ -0,0 +1,128 @@
+package bson
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/cristalhq/bson/bsonproto"
+)
+
+// Errors wrapped by [DecodeError].
+var (
+	// ErrShortInput is returned when the input ends before the element or document.
+	// It also matches [bsonproto.ErrDecodeShortInput] with [errors.Is].
+	ErrShortInput error = &protoError{msg: "bson: short input", proto: bsonproto.ErrDecodeShortInput}
+
+	// ErrInvalidInput is returned for malformed input: bad lengths, missing terminators or invalid values.
+	// It also matches [bsonproto.ErrDecodeInvalidInput] with [errors.Is].
+	ErrInvalidInput error = &protoError{msg: "bson: invalid input", proto: bsonproto.ErrDecodeInvalidInput}
+
+	// ErrInvalidType is returned for unknown element types.
+	ErrInvalidType = errors.New("bson: invalid element type")
+
+	// ErrTypeMismatch is returned when an element cannot be stored in a Go value of the given type.
+	ErrTypeMismatch = errors.New("bson: type mismatch")
+)
+
+// protoError is a sentinel error which matches the bsonproto error of the same meaning.
+type protoError struct {
+	msg   string
+	proto error
+}
+
+// Error implements [error].
+func (e *protoError) Error() string {
+	return e.msg
+}
+
+// Is reports whether the target is the bsonproto error.
+func (e *protoError) Is(target error) bool {
+	return target == e.proto
+}
+
+// DecodeError describes a failure to decode a BSON element.
+type DecodeError struct {
+	// Offset of the element in the input.
+	Offset int
+	// Type of the element, zero if the type is not known.
+	Type Type
+	// Path is a dot-notation path of the element, like items.3.price.
+	Path string
+	// Err is the cause, it wraps one of ErrShortInput, ErrInvalidInput, ErrInvalidType, ErrTypeMismatch
+	// or an error of the decoder options like ErrDuplicateKey.
+	Err error
+}
+
+// Error implements [error].
+func (e *DecodeError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("bson: decode error at offset ")
+	sb.WriteString(strconv.Itoa(e.Offset))
+	if e.Path != "" {
+		sb.WriteString(" in ")
+		sb.WriteString(e.Path)
+	}
+	if e.Type != 0 {
+		sb.WriteString(" (")
+		sb.WriteString(e.Type.String())
+		sb.WriteString(")")
+	}
+	sb.WriteString(": ")
+	sb.WriteString(e.Err.Error())
+	return sb.String()
+}
+
+// Unwrap returns the cause of the error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// withParent returns the error as seen from the parent document:
+// the element is stored by the key at the given offset.
+func (e *DecodeError) withParent(offset int, typ Type, key string) *DecodeError {
+	res := *e
+	res.Offset += offset
+	if res.Path == "" {
+		res.Path = key
+		res.Type = typ
+	} else {
+		res.Path = key + "." + res.Path
+	}
+	return &res
+}
+
+// UnsupportedValueError is returned by the encoder when a value cannot be encoded,
+// like a value that references itself.
+type UnsupportedValueError struct {
+	// Type of the value.
+	Type reflect.Type
+	// Path is a dot-notation path of the value, like items.3.parent.
+	Path string
+	// Reason describes why the value is not supported.
+	Reason string
+}
+
+// Error implements [error].
+func (e *UnsupportedValueError) Error() string {
+	if e.Path == "" {
+		return "bson: unsupported value of type " + e.Type.String() + ": " + e.Reason
+	}
+	return "bson: unsupported value of type " + e.Type.String() + " at " + e.Path + ": " + e.Reason
+}
+
+// withKey prepends the key to the path of [UnsupportedValueError], other errors are returned as is.
+func withKey(err error, key string) error {
+	e, ok := err.(*UnsupportedValueError)
+	if !ok {
+		return err
+	}
+	res := *e
+	if res.Path == "" {
+		res.Path = key
+	} else {
+		res.Path = key + "." + res.Path
+	}
+	return &res
+}
@@ -0,0 +1,675 @@
+package bson
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Filter is a compiled MongoDB query filter which can be matched against documents in memory.
+//
+// Supported operators:
+//
+//	$eq, $ne, $gt, $gte, $lt, $lte, $in, $nin,
+//	$exists, $type, $regex (with $options), $not,
+//	$elemMatch, $size, $all, $and, $or, $nor.
+//
+// Dotted paths like "items.sku" traverse nested documents and arrays the same way as MongoDB.
+type Filter struct {
+	m docMatcher
+}
+
+// NewFilter compiles a query given as D, M, map[string]any or RawObject.
+//
+// Example:
+//
+//	f, err := bson.NewFilter(bson.D{{"age", bson.D{{"$gte", 18}}}, {"tags", "go"}})
+func NewFilter(query any) (*Filter, error) {
+	if raw, ok := query.(RawObject); ok {
+		d, err := rawToD(raw, 1)
+		if err != nil {
+			return nil, err
+		}
+		query = d
+	}
+
+	doc, ok := asDocument(query)
+	if !ok {
+		return nil, fmt.Errorf("filter must be a document, got %T", query)
+	}
+	if err := checkCanonical(doc, 1); err != nil {
+		return nil, err
+	}
+
+	m, err := compileQuery(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{m: m}, nil
+}
+
+// Match reports whether the document matches the filter.
+// The document can be D, M, map[string]any, a struct or RawObject.
+func (f *Filter) Match(doc any) (bool, error) {
+	if raw, ok := doc.(RawObject); ok {
+		d, err := rawToD(raw, 1)
+		if err != nil {
+			return false, err
+		}
+		doc = d
+	}
+
+	d, ok := asDocument(doc)
+	if !ok {
+		return false, fmt.Errorf("cannot match %T, not a document", doc)
+	}
+	if err := checkCanonical(d, 1); err != nil {
+		return false, err
+	}
+	return f.m(d), nil
+}
+
+// docMatcher reports whether a document matches.
+type docMatcher func(doc D) bool
+
+// valueMatcher reports whether values found by a path match.
+// Values are empty when the path is missing.
+type valueMatcher func(values []any) bool
+
+func compileQuery(query D) (docMatcher, error) {
+	matchers := make([]docMatcher, 0, len(query))
+
+	for _, elem := range query {
+		var m docMatcher
+		var err error
+
+		switch key := elem.Key; {
+		case key == "$and" || key == "$or" || key == "$nor":
+			m, err = compileLogical(key, elem.Value)
+		case strings.HasPrefix(key, "$"):
+			err = fmt.Errorf("unknown top level operator %s", key)
+		default:
+			m, err = compileField(key, elem.Value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+
+	return func(doc D) bool {
+		for _, m := range matchers {
+			if !m(doc) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func compileLogical(op string, value any) (docMatcher, error) {
+	arr, ok := asArray(value)
+	if !ok || len(arr) == 0 {
+		return nil, fmt.Errorf("%s must be a nonempty array", op)
+	}
+
+	matchers := make([]docMatcher, len(arr))
+	for i, v := range arr {
+		doc, ok := asDocument(v)
+		if !ok {
+			return nil, fmt.Errorf("%s entries must be documents", op)
+		}
+		m, err := compileQuery(doc)
+		if err != nil {
+			return nil, err
+		}
+		matchers[i] = m
+	}
+
+	return func(doc D) bool {
+		for _, m := range matchers {
+			matched := m(doc)
+			switch {
+			case op == "$and" && !matched:
+				return false
+			case op == "$or" && matched:
+				return true
+			case op == "$nor" && matched:
+				return false
+			}
+		}
+		return op != "$or"
+	}, nil
+}
+
+func compileField(path string, cond any) (docMatcher, error) {
+	keys, err := splitPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := compileCondition(cond)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return func(doc D) bool {
+		return m(lookupValues(doc, keys))
+	}, nil
+}
+
+// compileCondition compiles either an operator document or a value for equality.
+func compileCondition(cond any) (valueMatcher, error) {
+	if doc, ok := asDocument(cond); ok && isOperatorDoc(doc) {
+		return compileOperators(doc)
+	}
+	if re, ok := cond.(Regex); ok {
+		return compileRegex(re)
+	}
+	return matchEq(cond), nil
+}
+
+func compileOperators(doc D) (valueMatcher, error) {
+	matchers := make([]valueMatcher, 0, len(doc))
+
+	for _, elem := range doc {
+		var m valueMatcher
+		var err error
+
+		switch op, val := elem.Key, elem.Value; op {
+		case "$eq":
+			m = matchEq(val)
+		case "$ne":
+			m = notMatch(matchEq(val))
+		case "$gt", "$gte", "$lt", "$lte":
+			m = matchCmp(op, val)
+		case "$in":
+			m, err = matchIn(op, val)
+		case "$nin":
+			m, err = matchIn(op, val)
+			m = notMatch(m)
+		case "$exists":
+			want := isTruthy(val)
+			m = func(values []any) bool {
+				return (len(values) > 0) == want
+			}
+		case "$type":
+			m, err = matchType(val)
+		case "$regex":
+			m, err = compileRegexOp(val, doc)
+		case "$options":
+			if _, ok := doc.Get("$regex"); !ok {
+				return nil, errors.New("$options needs a $regex")
+			}
+			continue
+		case "$not":
+			m, err = compileNot(val)
+		case "$elemMatch":
+			m, err = matchElem(val)
+		case "$size":
+			m, err = matchSize(val)
+		case "$all":
+			m, err = matchAll(val)
+		default:
+			err = fmt.Errorf("unknown operator %s", op)
+		}
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+
+	return func(values []any) bool {
+		for _, m := range matchers {
+			if !m(values) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func compileNot(val any) (valueMatcher, error) {
+	var m valueMatcher
+	var err error
+
+	switch {
+	case isRegex(val):
+		m, err = compileRegex(val.(Regex))
+	default:
+		doc, ok := asDocument(val)
+		if !ok || !isOperatorDoc(doc) {
+			return nil, errors.New("$not needs a regex or a document of operators")
+		}
+		m, err = compileOperators(doc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return notMatch(m), nil
+}
+
+func compileRegexOp(val any, doc D) (valueMatcher, error) {
+	var re Regex
+	switch val := val.(type) {
+	case string:
+		re.Pattern = val
+	case Regex:
+		re = val
+	default:
+		return nil, errors.New("$regex must be a string or a regex")
+	}
+	if opts, ok := doc.Get("$options"); ok {
+		s, ok := opts.(string)
+		if !ok {
+			return nil, errors.New("$options must be a string")
+		}
+		re.Options = s
+	}
+	return compileRegex(re)
+}
+
+func compileRegex(re Regex) (valueMatcher, error) {
+	r, err := re.Compile()
+	if err != nil {
+		return nil, err
+	}
+	return anyValue(func(v any) bool {
+		return matchRegex(r, re, v)
+	}), nil
+}
+
+func matchRegex(r *regexp.Regexp, re Regex, v any) bool {
+	switch v := v.(type) {
+	case string:
+		return r.MatchString(v)
+	case Regex:
+		return v == re
+	default:
+		return false
+	}
+}
+
+func matchEq(want any) valueMatcher {
+	if want == nil {
+		// null matches missing fields too.
+		return func(values []any) bool {
+			return len(values) == 0 || anyValue(isNilValue)(values)
+		}
+	}
+	return anyValue(func(v any) bool {
+		return sameBracket(v, want) && Compare(v, want) == 0
+	})
+}
+
+func matchCmp(op string, want any) valueMatcher {
+	cmp := func(v any) bool {
+		if !sameBracket(v, want) {
+			return false
+		}
+		c := Compare(v, want)
+		switch op {
+		case "$gt":
+			return c > 0
+		case "$gte":
+			return c >= 0
+		case "$lt":
+			return c < 0
+		default:
+			return c <= 0
+		}
+	}
+
+	if want == nil && (op == "$gte" || op == "$lte") {
+		return matchEq(nil)
+	}
+	return anyValue(cmp)
+}
+
+func matchIn(op string, val any) (valueMatcher, error) {
+	arr, ok := asArray(val)
+	if !ok {
+		return nil, fmt.Errorf("%s needs an array", op)
+	}
+
+	matchers := make([]valueMatcher, len(arr))
+	for i, v := range arr {
+		if re, ok := v.(Regex); ok {
+			m, err := compileRegex(re)
+			if err != nil {
+				return nil, err
+			}
+			matchers[i] = m
+		} else {
+			matchers[i] = matchEq(v)
+		}
+	}
+
+	return func(values []any) bool {
+		for _, m := range matchers {
+			if m(values) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func matchType(val any) (valueMatcher, error) {
+	match, err := compileType(val)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(values []any) bool {
+		for _, v := range values {
+			if match(v) {
+				return true
+			}
+			// elements of arrays are checked too, but not nested arrays.
+			if arr, ok := asArray(v); ok {
+				for _, elem := range arr {
+					if match(elem) {
+						return true
+					}
+				}
+			}
+		}
+		return false
+	}, nil
+}
+
+// compileType compiles a type alias, a type number or an array of them.
+func compileType(val any) (func(v any) bool, error) {
+	list, ok := asArray(val)
+	if !ok {
+		list = A{val}
+	}
+
+	types := make([]Type, 0, len(list))
+	var number bool
+	for _, v := range list {
+		switch v := v.(type) {
+		case string:
+			if v == "number" {
+				number = true
+				continue
+			}
+			typ, ok := typeAliases[v]
+			if !ok {
+				return nil, fmt.Errorf("unknown type alias %q", v)
+			}
+			types = append(types, typ)
+		default:
+			n, ok := asInt(v)
+			if !ok {
+				return nil, fmt.Errorf("$type must be a string or a number, got %T", v)
+			}
+			types = append(types, Type(n))
+		}
+	}
+
+	return func(v any) bool {
+		typ, ok := typeOf(v)
+		if !ok {
+			return false
+		}
+		if number && (typ == TypeDouble || typ == TypeInt32 || typ == TypeInt64 || typ == TypeDecimal) {
+			return true
+		}
+		for _, t := range types {
+			if t == typ {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func matchElem(val any) (valueMatcher, error) {
+	doc, ok := asDocument(val)
+	if !ok {
+		return nil, errors.New("$elemMatch needs a document")
+	}
+
+	match, err := compileElemMatch(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(values []any) bool {
+		for _, v := range values {
+			arr, ok := asArray(v)
+			if !ok {
+				continue
+			}
+			for _, elem := range arr {
+				if match(elem) {
+					return true
+				}
+			}
+		}
+		return false
+	}, nil
+}
+
+// compileElemMatch compiles a condition for a single array element:
+// operators are applied to the element itself, a query is applied to document elements.
+func compileElemMatch(doc D) (func(elem any) bool, error) {
+	if isOperatorDoc(doc) {
+		m, err := compileOperators(doc)
+		if err != nil {
+			return nil, err
+		}
+		return func(elem any) bool { return m([]any{elem}) }, nil
+	}
+
+	m, err := compileQuery(doc)
+	if err != nil {
+		return nil, err
+	}
+	return func(elem any) bool {
+		d, ok := asDocument(elem)
+		return ok && m(d)
+	}, nil
+}
+
+func matchSize(val any) (valueMatcher, error) {
+	size, ok := asInt(val)
+	if !ok || size < 0 {
+		return nil, errors.New("$size needs a non-negative integer")
+	}
+
+	return func(values []any) bool {
+		for _, v := range values {
+			if arr, ok := asArray(v); ok && len(arr) == size {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func matchAll(val any) (valueMatcher, error) {
+	arr, ok := asArray(val)
+	if !ok {
+		return nil, errors.New("$all needs an array")
+	}
+
+	matchers := make([]valueMatcher, len(arr))
+	for i, v := range arr {
+		m, err := compileCondition(v)
+		if err != nil {
+			return nil, err
+		}
+		matchers[i] = m
+	}
+
+	return func(values []any) bool {
+		if len(matchers) == 0 {
+			return false
+		}
+		for _, m := range matchers {
+			if !m(values) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// anyValue returns a matcher which checks each value and elements of array values.
+func anyValue(match func(v any) bool) valueMatcher {
+	return func(values []any) bool {
+		for _, v := range values {
+			if match(v) {
+				return true
+			}
+			if arr, ok := asArray(v); ok {
+				for _, elem := range arr {
+					if match(elem) {
+						return true
+					}
+				}
+			}
+		}
+		return false
+	}
+}
+
+func notMatch(m valueMatcher) valueMatcher {
+	return func(values []any) bool {
+		return !m(values)
+	}
+}
+
+// lookupValues returns all values found by the path, traversing arrays.
+func lookupValues(v any, keys []string) []any {
+	if len(keys) == 0 {
+		return []any{v}
+	}
+
+	if doc, ok := asDocument(v); ok {
+		child, ok := doc.Get(keys[0])
+		if !ok {
+			return nil
+		}
+		return lookupValues(child, keys[1:])
+	}
+
+	arr, ok := asArray(v)
+	if !ok {
+		return nil
+	}
+
+	var res []any
+	if idx, ok := parseIndex(keys[0]); ok && idx < len(arr) {
+		res = append(res, lookupValues(arr[idx], keys[1:])...)
+	}
+	for _, elem := range arr {
+		if _, ok := asDocument(elem); ok {
+			res = append(res, lookupValues(elem, keys)...)
+		}
+	}
+	return res
+}
+
+// sameBracket reports whether values can be compared by $eq, $gt and others.
+// MongoDB compares only values of the same type group.
+func sameBracket(a, b any) bool {
+	if isMinMaxKey(a) || isMinMaxKey(b) {
+		return true
+	}
+	oa, _ := canonicalOrder(a)
+	ob, _ := canonicalOrder(b)
+	return oa == ob
+}
+
+func isMinMaxKey(v any) bool {
+	switch v.(type) {
+	case MinKey, MaxKey:
+		return true
+	default:
+		return false
+	}
+}
+
+func isOperatorDoc(doc D) bool {
+	return len(doc) > 0 && strings.HasPrefix(doc[0].Key, "$")
+}
+
+func isRegex(v any) bool {
+	_, ok := v.(Regex)
+	return ok
+}
+
+func isTruthy(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	}
+	if n, ok := asNumber(v); ok {
+		return n != 0
+	}
+	return true
+}
+
+func asDocument(v any) (D, bool) {
+	switch v.(type) {
+	case D, M, map[string]any, RawObject:
+	default:
+		if typ, ok := typeOf(v); !ok || typ != TypeDocument {
+			return nil, false
+		}
+	}
+	order, val := canonicalOrder(v)
+	if order != orderDocument {
+		return nil, false
+	}
+	return val.(D), true
+}
+
+func asArray(v any) (A, bool) {
+	if typ, ok := typeOf(v); !ok || typ != TypeArray {
+		return nil, false
+	}
+	order, val := canonicalOrder(v)
+	if order != orderArray {
+		return nil, false
+	}
+	return val.(A), true
+}
+
+func asNumber(v any) (float64, bool) {
+	order, val := canonicalOrder(v)
+	if order != orderNumber {
+		return 0, false
+	}
+	switch val := val.(type) {
+	case int64:
+		return float64(val), true
+	case uint64:
+		return float64(val), true
+	case float64:
+		return val, true
+	default:
+		f, nan := bigNumber(val)
+		if nan {
+			return math.NaN(), true
+		}
+		res, _ := f.Float64()
+		return res, true
+	}
+}
+
+func asInt(v any) (int, bool) {
+	n, ok := asNumber(v)
+	if !ok || n != math.Trunc(n) {
+		return 0, false
+	}
+	return int(n), true
+}
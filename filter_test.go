@@ -0,0 +1,211 @@
+package bson
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFilter(t *testing.T) {
+	doc := D{
+		{"_id", int32(1)},
+		{"name", "John Smith"},
+		{"age", int32(42)},
+		{"score", 7.5},
+		{"nick", nil},
+		{"tags", A{"go", "mongo", "bson"}},
+		{"address", D{{"city", "Berlin"}, {"zip", "10115"}}},
+		{"items", A{
+			D{{"sku", "a-1"}, {"qty", int32(2)}},
+			D{{"sku", "b-2"}, {"qty", int32(10)}},
+		}},
+		{"matrix", A{A{int32(1), int32(2)}, A{int32(3)}}},
+		{"created", time.Unix(1691690746, 0)},
+	}
+
+	testCases := []struct {
+		query any
+		want  bool
+	}{
+		{D{}, true},
+		{D{{"name", "John Smith"}}, true},
+		{D{{"name", "john"}}, false},
+		{D{{"age", int64(42)}}, true},
+		{D{{"age", 42.0}}, true},
+		{D{{"age", "42"}}, false},
+		{M{"age": int32(42), "score": 7.5}, true},
+
+		// comparison, type bracketing.
+		{D{{"age", D{{"$gt", int32(40)}}}}, true},
+		{D{{"age", D{{"$gt", int32(42)}}}}, false},
+		{D{{"age", D{{"$gte", int32(42)}, {"$lt", 50.0}}}}, true},
+		{D{{"age", D{{"$lte", int32(41)}}}}, false},
+		{D{{"age", D{{"$gt", "a"}}}}, false},
+		{D{{"age", D{{"$ne", int32(42)}}}}, false},
+		{D{{"age", D{{"$ne", int32(1)}}}}, true},
+		{D{{"created", D{{"$gt", time.Unix(1, 0)}}}}, true},
+		{D{{"age", D{{"$gt", MinKey{}}}}}, true},
+
+		// null and missing.
+		{D{{"nick", nil}}, true},
+		{D{{"missing", nil}}, true},
+		{D{{"age", nil}}, false},
+		{D{{"missing", D{{"$ne", nil}}}}, false},
+		{D{{"age", D{{"$ne", nil}}}}, true},
+		{D{{"missing", D{{"$gte", nil}}}}, true},
+
+		// $in, $nin.
+		{D{{"age", D{{"$in", A{int32(1), int32(42)}}}}}, true},
+		{D{{"age", D{{"$in", A{}}}}}, false},
+		{D{{"tags", D{{"$in", A{"x", "go"}}}}}, true},
+		{D{{"name", D{{"$in", A{Regex{Pattern: "^john", Options: "i"}}}}}}, true},
+		{D{{"age", D{{"$nin", A{int32(1), int32(2)}}}}}, true},
+		{D{{"tags", D{{"$nin", A{"go"}}}}}, false},
+
+		// $exists.
+		{D{{"nick", D{{"$exists", true}}}}, true},
+		{D{{"missing", D{{"$exists", true}}}}, false},
+		{D{{"missing", D{{"$exists", false}}}}, true},
+		{D{{"items.sku", D{{"$exists", int32(1)}}}}, true},
+
+		// $type.
+		{D{{"age", D{{"$type", "int"}}}}, true},
+		{D{{"age", D{{"$type", "number"}}}}, true},
+		{D{{"age", D{{"$type", int32(16)}}}}, true},
+		{D{{"age", D{{"$type", A{"string", "double"}}}}}, false},
+		{D{{"tags", D{{"$type", "array"}}}}, true},
+		{D{{"tags", D{{"$type", "string"}}}}, true},
+		{D{{"nick", D{{"$type", "null"}}}}, true},
+		{D{{"address", D{{"$type", "object"}}}}, true},
+
+		// $regex.
+		{D{{"name", Regex{Pattern: "smith$", Options: "i"}}}, true},
+		{D{{"name", D{{"$regex", "^John"}}}}, true},
+		{D{{"name", D{{"$regex", "^john"}, {"$options", "i"}}}}, true},
+		{D{{"name", D{{"$regex", "^john"}}}}, false},
+		{D{{"tags", D{{"$regex", "^mon"}}}}, true},
+		{D{{"age", D{{"$regex", "4"}}}}, false},
+
+		// $not.
+		{D{{"age", D{{"$not", D{{"$gt", int32(50)}}}}}}, true},
+		{D{{"name", D{{"$not", Regex{Pattern: "^John"}}}}}, false},
+		{D{{"missing", D{{"$not", D{{"$gt", int32(1)}}}}}}, true},
+
+		// dotted paths and arrays.
+		{D{{"address.city", "Berlin"}}, true},
+		{D{{"address.city", "Paris"}}, false},
+		{D{{"items.sku", "b-2"}}, true},
+		{D{{"items.1.sku", "b-2"}}, true},
+		{D{{"items.0.sku", "b-2"}}, false},
+		{D{{"items.qty", D{{"$gt", int32(5)}}}}, true},
+		{D{{"tags", "mongo"}}, true},
+		{D{{"tags", A{"go", "mongo", "bson"}}}, true},
+		{D{{"tags", A{"go", "mongo"}}}, false},
+		{D{{"tags.1", "mongo"}}, true},
+		{D{{"matrix", A{int32(3)}}}, true},
+		{D{{"name.first", "John"}}, false},
+
+		// $elemMatch.
+		{D{{"items", D{{"$elemMatch", D{{"sku", "a-1"}, {"qty", D{{"$gt", int32(5)}}}}}}}}, false},
+		{D{{"items", D{{"$elemMatch", D{{"sku", "b-2"}, {"qty", D{{"$gt", int32(5)}}}}}}}}, true},
+		{D{{"tags", D{{"$elemMatch", D{{"$regex", "^b"}}}}}}, true},
+		{D{{"address", D{{"$elemMatch", D{{"city", "Berlin"}}}}}}, false},
+
+		// $size.
+		{D{{"tags", D{{"$size", int32(3)}}}}, true},
+		{D{{"tags", D{{"$size", 2}}}}, false},
+		{D{{"name", D{{"$size", 0}}}}, false},
+
+		// $all.
+		{D{{"tags", D{{"$all", A{"go", "bson"}}}}}, true},
+		{D{{"tags", D{{"$all", A{"go", "rust"}}}}}, false},
+		{D{{"tags", D{{"$all", A{}}}}}, false},
+
+		// logical.
+		{D{{"$and", A{D{{"age", int32(42)}}, D{{"name", "John Smith"}}}}}, true},
+		{D{{"$and", A{D{{"age", int32(42)}}, D{{"name", "x"}}}}}, false},
+		{D{{"$or", A{D{{"age", int32(1)}}, D{{"name", "John Smith"}}}}}, true},
+		{D{{"$or", A{D{{"age", int32(1)}}, D{{"name", "x"}}}}}, false},
+		{D{{"$nor", A{D{{"age", int32(1)}}, D{{"name", "x"}}}}}, true},
+		{D{{"$nor", A{D{{"age", int32(42)}}}}}, false},
+	}
+
+	for _, tc := range testCases {
+		f, err := NewFilter(tc.query)
+		mustOk(t, err)
+
+		got, err := f.Match(doc)
+		mustOk(t, err)
+		if got != tc.want {
+			t.Fatalf("query %v: have %v, want %v", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestFilterMatchTypes(t *testing.T) {
+	f, err := NewFilter(M{"name": "john", "address.city": "Berlin"})
+	mustOk(t, err)
+
+	type address struct {
+		City string `bson:"city"`
+	}
+	type user struct {
+		Name    string  `bson:"name"`
+		Address address `bson:"address"`
+	}
+
+	docs := []any{
+		D{{"name", "john"}, {"address", M{"city": "Berlin"}}},
+		M{"name": "john", "address": D{{"city", "Berlin"}}},
+		map[string]any{"name": "john", "address": map[string]any{"city": "Berlin"}},
+		user{Name: "john", Address: address{City: "Berlin"}},
+		&user{Name: "john", Address: address{City: "Berlin"}},
+		RawObject(must(Marshal(D{{"name", "john"}, {"address", D{{"city", "Berlin"}}}}))),
+	}
+	for _, doc := range docs {
+		ok, err := f.Match(doc)
+		mustOk(t, err)
+		mustEqual(t, ok, true)
+	}
+
+	_, err = f.Match("string")
+	mustFail(t, err)
+
+	_, err = f.Match(RawObject{1, 2, 3})
+	mustFail(t, err)
+
+	_, err = f.Match(D{{"x", RawObject{1, 2, 3}}})
+	mustFail(t, err)
+
+	_, err = f.Match(D{{"name", "john"}, {"c", make(chan int)}})
+	mustFail(t, err)
+}
+
+func TestFilterErrors(t *testing.T) {
+	queries := []any{
+		"not a doc",
+		D{{"$where", "x"}},
+		D{{"a", D{{"$foo", int32(1)}}}},
+		D{{"$or", A{}}},
+		D{{"$and", "x"}},
+		D{{"a", D{{"$in", int32(1)}}}},
+		D{{"a", D{{"$size", "x"}}}},
+		D{{"a", D{{"$size", 1.5}}}},
+		D{{"a", D{{"$type", "nope"}}}},
+		D{{"a", D{{"$regex", "("}}}},
+		D{{"a", D{{"$regex", int32(1)}}}},
+		D{{"a", D{{"$options", "i"}}}},
+		D{{"a", D{{"$not", int32(1)}}}},
+		D{{"a", D{{"$elemMatch", int32(1)}}}},
+		D{{"a", D{{"$all", int32(1)}}}},
+		D{{"a..b", int32(1)}},
+		D{{"a", RawObject{1, 2}}},
+		D{{"a", D{{"$in", A{make(chan int)}}}}},
+	}
+
+	for _, q := range queries {
+		_, err := NewFilter(q)
+		if err == nil {
+			t.Fatalf("query %v must fail", q)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package bson
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidKey is returned when a document has a key not allowed by [KeyRules].
+var ErrInvalidKey = errors.New("bson: invalid key")
+
+// KeyRules configures [ValidateKeys].
+// Zero value enables all the rules, as required by MongoDB for stored documents.
+type KeyRules struct {
+	// AllowDollar allows top-level keys starting with $.
+	// By default only update operators like $set and DBRef keys $ref, $id and $db
+	// are allowed, nested keys are not checked as the server accepts them.
+	AllowDollar bool
+
+	// AllowDots allows keys containing a dot.
+	AllowDots bool
+
+	// AllowEmpty allows empty keys.
+	AllowEmpty bool
+
+	// AllowArrayID allows top-level _id to be an array.
+	AllowArrayID bool
+}
+
+// dbRefKeys are $-prefixed keys allowed in stored documents.
+var dbRefKeys = map[string]bool{
+	"$ref": true,
+	"$id":  true,
+	"$db":  true,
+}
+
+// isKnownDollarKey reports whether the $-prefixed key is a DBRef key or an update operator.
+func isKnownDollarKey(key string) bool {
+	_, ok := updateOperators[key]
+	return ok || dbRefKeys[key]
+}
+
+// ValidateKeys checks keys of the document including nested documents and arrays.
+// The document can be D, M, map[string]any, a struct or RawObject.
+// Nil rules are the same as zero [KeyRules].
+// Documents nested deeper than [DefaultMaxDepth] return [ErrMaxDepthExceeded].
+func ValidateKeys(v any, rules *KeyRules) error {
+	raw, ok := v.(RawObject)
+	if !ok {
+		var err error
+		if raw, err = ToRaw(v); err != nil {
+			return err
+		}
+	}
+	if rules == nil {
+		rules = &KeyRules{}
+	}
+	return validateKeys(raw, rules, "", 1, DefaultMaxDepth, false)
+}
+
+// SetKeyRules enables validation of keys in encoded documents, see [ValidateKeys].
+// Nil rules disable validation, this is the default.
+func (enc *Encoder) SetKeyRules(rules *KeyRules) {
+	enc.keyRules = rules
+}
+
+// validateKeys checks keys of the document at the given nesting level starting from 1.
+func validateKeys(data []byte, rules *KeyRules, path string, depth, max int, array bool) error {
+	if depth > max {
+		return depthError(max)
+	}
+	iter, err := newReader(data)
+	if err != nil {
+		return err
+	}
+
+	for iter.Next() {
+		typ, name, element := iter.Peek()
+		key := trimlast(name)
+		keyPath := joinPath(path, key)
+
+		if !array {
+			switch {
+			case key == "" && !rules.AllowEmpty:
+				return fmt.Errorf("%w: %s: key must not be empty", ErrInvalidKey, keyPath)
+			case depth == 1 && strings.HasPrefix(key, "$") && !rules.AllowDollar && !isKnownDollarKey(key):
+				return fmt.Errorf("%w: %s: key must not start with $", ErrInvalidKey, keyPath)
+			case strings.Contains(key, ".") && !rules.AllowDots:
+				return fmt.Errorf("%w: %s: key must not contain a dot", ErrInvalidKey, keyPath)
+			case depth == 1 && key == "_id" && typ == TypeArray && !rules.AllowArrayID:
+				return fmt.Errorf("%w: _id must not be an array", ErrInvalidKey)
+			}
+		}
+
+		if typ == TypeDocument || typ == TypeArray {
+			if err := validateKeys(element, rules, keyPath, depth+1, max, typ == TypeArray); err != nil {
+				return err
+			}
+		}
+	}
+	return iter.Err()
+}
@@ -0,0 +1,100 @@
+package bson
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestValidateKeys(t *testing.T) {
+	valid := D{
+		{"_id", int32(1)},
+		{"ref", D{{"$ref", "coll"}, {"$id", int32(1)}, {"$db", "db"}}},
+		{"list", A{D{{"a", int32(1)}}, int32(2)}},
+		{"nested", D{{"_id", A{int32(1)}}, {"$x", int32(1)}}},
+		{"list2", A{D{{"$y", int32(1)}}}},
+	}
+	mustOk(t, ValidateKeys(valid, nil))
+	mustOk(t, ValidateKeys(D{{"$set", D{{"a", int32(1)}}}, {"$inc", D{{"n", int32(1)}}}}, nil))
+	mustOk(t, ValidateKeys(D{{"$ref", "coll"}, {"$id", int32(1)}}, nil))
+	mustOk(t, ValidateKeys(RawObject(must(Marshal(valid))), nil))
+
+	type user struct {
+		Name string `bson:"name"`
+	}
+	mustOk(t, ValidateKeys(user{Name: "x"}, nil))
+	mustOk(t, ValidateKeys(M{"a": M{"b": int32(1)}}, nil))
+
+	testCases := []struct {
+		doc   any
+		rules KeyRules
+		want  string
+	}{
+		{
+			doc:   D{{"$foo", int32(1)}},
+			rules: KeyRules{AllowDots: true, AllowEmpty: true, AllowArrayID: true},
+			want:  "bson: invalid key: $foo: key must not start with $",
+		},
+		{
+			doc:   M{"a": int32(1), "$where": "x"},
+			rules: KeyRules{},
+			want:  "bson: invalid key: $where: key must not start with $",
+		},
+		{
+			doc:   D{{"a", D{{"b.c", int32(1)}}}},
+			rules: KeyRules{AllowDollar: true},
+			want:  "bson: invalid key: a.b.c: key must not contain a dot",
+		},
+		{
+			doc:   M{"a": M{"": int32(1)}},
+			rules: KeyRules{},
+			want:  "bson: invalid key: a.: key must not be empty",
+		},
+		{
+			doc:   D{{"_id", A{int32(1)}}},
+			rules: KeyRules{AllowDollar: true, AllowDots: true, AllowEmpty: true},
+			want:  "bson: invalid key: _id must not be an array",
+		},
+	}
+
+	for _, tc := range testCases {
+		rules := tc.rules
+		err := ValidateKeys(tc.doc, &rules)
+		mustEqual(t, errors.Is(err, ErrInvalidKey), true)
+		mustEqual(t, err.Error(), tc.want)
+	}
+
+	all := KeyRules{AllowDollar: true, AllowDots: true, AllowEmpty: true, AllowArrayID: true}
+	for _, tc := range testCases {
+		mustOk(t, ValidateKeys(tc.doc, &all))
+	}
+
+	mustFail(t, ValidateKeys(RawObject{1, 2, 3}, nil))
+
+	deep := D{{"x", int32(1)}}
+	for i := 1; i < DefaultMaxDepth; i++ {
+		deep = D{{"x", deep}}
+	}
+	raw := must(Marshal(deep))
+	mustOk(t, ValidateKeys(RawObject(raw), nil))
+
+	err := ValidateKeys(D{{"x", RawObject(raw)}}, nil)
+	mustEqual(t, errors.Is(err, ErrMaxDepthExceeded), true)
+}
+
+func TestEncoderKeyRules(t *testing.T) {
+	doc := D{{"a.b", int32(1)}}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	mustOk(t, enc.Encode(doc))
+
+	buf.Reset()
+	enc.SetKeyRules(&KeyRules{})
+	err := enc.Encode(doc)
+	mustEqual(t, errors.Is(err, ErrInvalidKey), true)
+	mustEqual(t, buf.Len(), 0)
+
+	enc.SetKeyRules(&KeyRules{AllowDots: true})
+	mustOk(t, enc.Encode(doc))
+}
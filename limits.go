@@ -0,0 +1,100 @@
+package bson
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// DefaultMaxDepth is the default max nesting depth of documents and arrays.
+	// The top-level document has depth 1.
+	DefaultMaxDepth = 100
+
+	// DefaultMaxDocumentSize is the default max size of a document in bytes, same as in MongoDB.
+	DefaultMaxDocumentSize = 16 << 20
+)
+
+var (
+	// ErrMaxDepthExceeded is returned when documents are nested deeper than allowed.
+	ErrMaxDepthExceeded = errors.New("bson: max depth exceeded")
+
+	// ErrDocumentTooLarge is returned when a document is larger than allowed.
+	ErrDocumentTooLarge = errors.New("bson: document is too large")
+)
+
+// SetMaxDepth sets the max nesting depth of encoded documents and arrays.
+// Zero or negative value resets it to [DefaultMaxDepth].
+func (enc *Encoder) SetMaxDepth(n int) {
+	enc.maxDepth = n
+}
+
+// SetMaxDocumentSize sets the max size of an encoded document in bytes.
+// Zero or negative value resets it to [DefaultMaxDocumentSize].
+func (enc *Encoder) SetMaxDocumentSize(n int) {
+	enc.maxSize = n
+}
+
+// SetMaxDepth sets the max nesting depth of decoded documents and arrays.
+// Zero or negative value resets it to [DefaultMaxDepth].
+func (dec *Decoder) SetMaxDepth(n int) {
+	dec.maxDepth = n
+}
+
+// SetMaxDocumentSize sets the max size of a decoded document in bytes.
+// Zero or negative value resets it to [DefaultMaxDocumentSize].
+func (dec *Decoder) SetMaxDocumentSize(n int) {
+	dec.maxSize = n
+}
+
+// enter is called before writing a nested document or array.
+func (enc *Encoder) enter() error {
+	if max := limit(enc.maxDepth, DefaultMaxDepth); enc.depth >= max {
+		return fmt.Errorf("%w: max depth is %d", ErrMaxDepthExceeded, max)
+	}
+	enc.depth++
+	return nil
+}
+
+// leave is called after writing a nested document or array.
+func (enc *Encoder) leave() {
+	enc.depth--
+}
+
+func (enc *Encoder) checkSize(size int) error {
+	if max := limit(enc.maxSize, DefaultMaxDocumentSize); size > max {
+		return fmt.Errorf("%w: %d bytes, max is %d", ErrDocumentTooLarge, size, max)
+	}
+	return nil
+}
+
+func (dec *Decoder) checkSize(size int) error {
+	if max := limit(dec.maxSize, DefaultMaxDocumentSize); size > max {
+		return &DecodeError{Err: fmt.Errorf("%w: %d bytes, max is %d", ErrDocumentTooLarge, size, max)}
+	}
+	return nil
+}
+
+// enter is called before decoding a nested document or array.
+func (ds *decodeState) enter() error {
+	if max := limit(ds.maxDepth, DefaultMaxDepth); ds.depth >= max {
+		return depthError(max)
+	}
+	ds.depth++
+	return nil
+}
+
+// leave is called after decoding a nested document or array.
+func (ds *decodeState) leave() {
+	ds.depth--
+}
+
+func depthError(max int) *DecodeError {
+	return &DecodeError{Err: fmt.Errorf("%w: max depth is %d", ErrMaxDepthExceeded, max)}
+}
+
+func limit(v, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,155 @@
+package bson
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncoderLimits(t *testing.T) {
+	nested := func(depth int) D {
+		doc := D{{"x", int32(1)}}
+		for i := 1; i < depth; i++ {
+			doc = D{{"x", doc}}
+		}
+		return doc
+	}
+
+	_, err := Marshal(nested(DefaultMaxDepth))
+	mustOk(t, err)
+
+	_, err = Marshal(nested(DefaultMaxDepth + 1))
+	mustEqual(t, errors.Is(err, ErrMaxDepthExceeded), true)
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetMaxDepth(3)
+	mustOk(t, enc.Encode(D{{"a", A{D{{"b", int32(1)}}}}}))
+	err = enc.Encode(D{{"a", A{A{D{{"b", int32(1)}}}}}})
+	mustEqual(t, errors.Is(err, ErrMaxDepthExceeded), true)
+
+	type inner struct{ Vals []int32 }
+	type outer struct{ In inner }
+	err = enc.Encode(outer{In: inner{Vals: []int32{1}}})
+	mustOk(t, err)
+	err = enc.Encode(M{"a": outer{}})
+	mustEqual(t, errors.Is(err, ErrMaxDepthExceeded), true)
+
+	big := D{{"s", string(make([]byte, DefaultMaxDocumentSize))}}
+	_, err = Marshal(big)
+	mustEqual(t, errors.Is(err, ErrDocumentTooLarge), true)
+
+	enc = NewEncoder(&buf)
+	enc.SetMaxDocumentSize(16)
+	mustOk(t, enc.Encode(D{{"a", int32(1)}}))
+	err = enc.Encode(D{{"a", "long string"}})
+	mustEqual(t, errors.Is(err, ErrDocumentTooLarge), true)
+
+	// the size is counted for the document only, not for dst.
+	dst := make([]byte, 100)
+	_, err = MarshalTo(dst, D{{"a", int32(1)}})
+	mustOk(t, err)
+}
+
+func TestDecoderLimits(t *testing.T) {
+	// nested builds a document nested depth times without the encoder limits.
+	nested := func(depth int) []byte {
+		doc := must(Marshal(D{{"x", int32(1)}}))
+		for i := 1; i < depth; i++ {
+			n := len(doc) + 8
+			b := []byte{byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24), byte(TypeDocument), 'x', 0}
+			doc = append(append(b, doc...), 0)
+		}
+		return doc
+	}
+
+	var d D
+	mustOk(t, Unmarshal(nested(DefaultMaxDepth), &d))
+
+	err := Unmarshal(nested(10_000), &d)
+	mustEqual(t, errors.Is(err, ErrMaxDepthExceeded), true)
+
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("want DecodeError, got %v", err)
+	}
+
+	dec := NewDecodeBytes(nested(3))
+	dec.SetMaxDepth(2)
+	err = dec.Decode(&d)
+	mustEqual(t, errors.Is(err, ErrMaxDepthExceeded), true)
+
+	// the limit is checked for every destination type.
+	var node struct {
+		X struct {
+			X struct {
+				X int32 `bson:"x"`
+			} `bson:"x"`
+		} `bson:"x"`
+	}
+	for _, v := range []any{&map[string]any{}, &M{}, &node, new(any)} {
+		dec := NewDecodeBytes(nested(3))
+		dec.SetMaxDepth(2)
+		mustEqual(t, errors.Is(dec.Decode(v), ErrMaxDepthExceeded), true)
+
+		dec = NewDecodeBytes(nested(3))
+		dec.SetMaxDepth(3)
+		mustOk(t, dec.Decode(v))
+	}
+
+	arrays := must(Marshal(D{{"a", A{A{A{int32(1)}}}}}))
+	var typed struct {
+		A [][][]int32 `bson:"a"`
+	}
+	for _, v := range []any{&d, &typed} {
+		dec = NewDecodeBytes(arrays)
+		dec.SetMaxDepth(3)
+		mustEqual(t, errors.Is(dec.Decode(v), ErrMaxDepthExceeded), true)
+
+		dec = NewDecodeBytes(arrays)
+		dec.SetMaxDepth(4)
+		mustOk(t, dec.Decode(v))
+	}
+
+	raw := must(Marshal(D{{"a", "long string"}}))
+	for name, dec := range map[string]*Decoder{
+		"bytes":  NewDecodeBytes(raw),
+		"reader": NewDecoder(bytes.NewReader(raw)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			dec.SetMaxDocumentSize(16)
+			err := dec.Decode(&d)
+			mustEqual(t, errors.Is(err, ErrDocumentTooLarge), true)
+		})
+	}
+}
+
+func TestRawDepthLimits(t *testing.T) {
+	deep := D{{"x", int32(1)}}
+	for i := 1; i < DefaultMaxDepth; i++ {
+		deep = D{{"x", deep}}
+	}
+	ok := RawObject(must(Marshal(deep)))
+	tooDeep := RawObject(must(Marshal(D{{"x", ok}})))
+
+	f := must(NewFilter(D{{"y", int32(1)}}))
+	_, err := f.Match(ok)
+	mustOk(t, err)
+	_, err = f.Match(tooDeep)
+	mustEqual(t, errors.Is(err, ErrMaxDepthExceeded), true)
+
+	_, err = Project(ok, D{{"x", int32(1)}})
+	mustOk(t, err)
+	_, err = Project(tooDeep, D{{"x", int32(1)}})
+	mustEqual(t, errors.Is(err, ErrMaxDepthExceeded), true)
+
+	s := must(NewSchema(D{{"bsonType", "object"}}))
+	mustOk(t, s.Validate(ok))
+	err = s.Validate(tooDeep)
+	mustEqual(t, errors.Is(err, ErrMaxDepthExceeded), true)
+
+	_, err = NewFilter(tooDeep)
+	mustEqual(t, errors.Is(err, ErrMaxDepthExceeded), true)
+	_, err = NewSchema(tooDeep)
+	mustEqual(t, errors.Is(err, ErrMaxDepthExceeded), true)
+}
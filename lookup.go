@@ -0,0 +1,80 @@
+package bson
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrElementNotFound is returned when an element is missing in a document.
+var ErrElementNotFound = errors.New("element not found")
+
+// Lookup decodes a single nested element of a raw document into T.
+// Path is a list of keys for documents and indices for arrays.
+//
+// Example:
+//
+//	city, err := bson.Lookup[string](raw, "address", "city")
+//	sku, err := bson.Lookup[string](raw, "items", "3", "sku")
+func Lookup[T any](raw []byte, path ...string) (T, error) {
+	var res T
+	if len(path) == 0 {
+		return res, errors.New("empty lookup path")
+	}
+
+	typ, element := TypeDocument, raw
+	for i, key := range path {
+		if typ != TypeDocument && typ != TypeArray {
+			return res, fmt.Errorf("cannot lookup %q in %s: element is not a document or array",
+				key, strings.Join(path[:i], "."))
+		}
+
+		var ok bool
+		var err error
+		typ, element, ok, err = lookupElement(element, key)
+		if err != nil {
+			return res, err
+		}
+		if !ok {
+			return res, fmt.Errorf("%w: %s", ErrElementNotFound, strings.Join(path[:i+1], "."))
+		}
+	}
+
+	if err := decodeElementInto(typ, element, &res); err != nil {
+		return res, fmt.Errorf("lookup %s: %w", strings.Join(path, "."), err)
+	}
+	return res, nil
+}
+
+func lookupElement(data []byte, key string) (Type, []byte, bool, error) {
+	iter, err := newReader(data)
+	if err != nil {
+		return 0, nil, false, err
+	}
+
+	for iter.Next() {
+		typ, name, element := iter.Peek()
+		if trimlast(name) == key {
+			return typ, element, true, nil
+		}
+	}
+	return 0, nil, false, iter.Err()
+}
+
+// decodeElementInto decodes a single element into a value pointed by v.
+func decodeElementInto[T any](typ Type, element []byte, v *T) error {
+	var ds decodeState
+	return ds.decodeInto(typ, element, reflect.ValueOf(v).Elem())
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
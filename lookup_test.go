@@ -0,0 +1,64 @@
+package bson
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	raw := must(Marshal(D{
+		{"name", "john"},
+		{"age", int32(42)},
+		{"address", D{{"city", "Berlin"}, {"zip", int64(10115)}}},
+		{"items", A{
+			D{{"sku", "a-1"}},
+			D{{"sku", "b-2"}, {"qty", int32(3)}},
+		}},
+	}))
+
+	mustEqual(t, must(Lookup[string](raw, "name")), "john")
+	mustEqual(t, must(Lookup[int32](raw, "age")), int32(42))
+	mustEqual(t, must(Lookup[int](raw, "age")), 42)
+	mustEqual(t, must(Lookup[float64](raw, "age")), float64(42))
+	mustEqual(t, must(Lookup[string](raw, "address", "city")), "Berlin")
+	mustEqual(t, must(Lookup[int64](raw, "address", "zip")), int64(10115))
+	mustEqual(t, must(Lookup[string](raw, "items", "1", "sku")), "b-2")
+	mustDeepEqual(t, must(Lookup[any](raw, "items", "1", "qty")), any(int32(3)))
+
+	mustDeepEqual(t, must(Lookup[D](raw, "address")), D{{"city", "Berlin"}, {"zip", int64(10115)}})
+	mustDeepEqual(t, must(Lookup[M](raw, "items", "0")), M{"sku": "a-1"})
+	mustEqual(t, len(must(Lookup[A](raw, "items"))), 2)
+
+	type address struct {
+		City string
+	}
+	raw2 := must(Marshal(D{{"address", D{{"City", "Paris"}}}}))
+	mustEqual(t, must(Lookup[address](raw2, "address")), address{City: "Paris"})
+
+	_, err := Lookup[string](raw, "address", "street")
+	mustEqual(t, errors.Is(err, ErrElementNotFound), true)
+	mustEqual(t, err.Error(), "element not found: address.street")
+
+	_, err = Lookup[string](raw, "name", "first")
+	mustFail(t, err)
+
+	_, err = Lookup[string](raw, "age")
+	mustFail(t, err)
+
+	nums := must(Marshal(D{{"f", 3.7}, {"big", int32(300)}, {"neg", int64(-1)}, {"nan", math.NaN()}}))
+	for _, err := range []error{
+		func() error { _, err := Lookup[int](nums, "f"); return err }(),
+		func() error { _, err := Lookup[uint8](nums, "big"); return err }(),
+		func() error { _, err := Lookup[uint64](nums, "neg"); return err }(),
+		func() error { _, err := Lookup[int64](nums, "nan"); return err }(),
+	} {
+		mustEqual(t, errors.Is(err, ErrTypeMismatch), true)
+	}
+	mustEqual(t, must(Lookup[float32](nums, "f")), float32(3.7))
+	mustEqual(t, must(Lookup[int16](nums, "big")), int16(300))
+	mustEqual(t, must(Lookup[float64](nums, "neg")), float64(-1))
+
+	_, err = Lookup[string](raw)
+	mustFail(t, err)
+}
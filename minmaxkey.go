@@ -0,0 +1,19 @@
+package bson
+
+// MinKey represents BSON type MinKey.
+// It compares lower than all other BSON values.
+type MinKey struct{}
+
+// String returns a string representation of MinKey.
+func (MinKey) String() string {
+	return "MinKey()"
+}
+
+// MaxKey represents BSON type MaxKey.
+// It compares higher than all other BSON values.
+type MaxKey struct{}
+
+// String returns a string representation of MaxKey.
+func (MaxKey) String() string {
+	return "MaxKey()"
+}
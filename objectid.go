@@ -1,6 +1,7 @@
 package bson
 
 import (
+	"bytes"
 	"crypto/rand"
 	"encoding"
 	"encoding/hex"
@@ -16,38 +17,145 @@ var ErrBadObjectID = errors.New("not a valid ObjectID")
 // ObjectID represents BSON object ID.
 type ObjectID [12]byte
 
+// NilObjectID is the zero value for ObjectID.
+var NilObjectID ObjectID
+
 // NewObjectID returns a new ObjectID.
+// It uses the default [ObjectIDGenerator].
 func NewObjectID() ObjectID {
-	return NewObjectIDWithTime(time.Now())
+	return defaultObjectIDGenerator.Load().New()
 }
 
 // NewObjectIDWithTime returns a new ObjectID.
+// It uses the default [ObjectIDGenerator].
 func NewObjectIDWithTime(t time.Time) ObjectID {
+	return defaultObjectIDGenerator.Load().NewWithTime(t)
+}
+
+// SetObjectIDGenerator replaces the generator used by [NewObjectID] and [NewObjectIDWithTime].
+// It returns the previous generator, so it can be restored later.
+// Passing nil resets to the default (randomly seeded) generator.
+func SetObjectIDGenerator(g *ObjectIDGenerator) *ObjectIDGenerator {
+	if g == nil {
+		g = globalObjectIDGenerator
+	}
+	return defaultObjectIDGenerator.Swap(g)
+}
+
+// ObjectIDGenerator generates ObjectIDs.
+//
+// The zero value is ready to use and is deterministic except for the clock:
+// process-unique bytes are zeroed and the counter starts at 0.
+// Use [NewObjectIDGenerator] for a randomly seeded generator.
+type ObjectIDGenerator struct {
+	// Now returns the current time. If nil, [time.Now] is used.
+	Now func() time.Time
+
+	// ProcessID is 5 bytes unique to the machine and process.
+	ProcessID [5]byte
+
+	// Counter returns the next counter value, only the lower 24 bits are used.
+	// If nil, an internal atomic counter is used.
+	Counter func() uint32
+
+	counter atomic.Uint32
+}
+
+// NewObjectIDGenerator returns a new generator with random process-unique bytes and counter.
+func NewObjectIDGenerator() *ObjectIDGenerator {
+	g := &ObjectIDGenerator{}
+	must(rand.Read(g.ProcessID[:]))
+	g.counter.Store(mathrand.Uint32())
+	return g
+}
+
+// New returns a new ObjectID with the generator's current time.
+func (g *ObjectIDGenerator) New() ObjectID {
+	now := time.Now
+	if g.Now != nil {
+		now = g.Now
+	}
+	return g.NewWithTime(now())
+}
+
+// NewWithTime returns a new ObjectID with the given time.
+func (g *ObjectIDGenerator) NewWithTime(t time.Time) ObjectID {
+	var c uint32
+	if g.Counter != nil {
+		c = g.Counter()
+	} else {
+		c = g.counter.Add(1)
+	}
+
+	oid := ObjectIDFromTime(t)
+
+	oid[4] = g.ProcessID[0]
+	oid[5] = g.ProcessID[1]
+	oid[6] = g.ProcessID[2]
+	oid[7] = g.ProcessID[3]
+	oid[8] = g.ProcessID[4]
+
+	oid[9] = byte(c >> 16)
+	oid[10] = byte(c >> 8)
+	oid[11] = byte(c)
+	return oid
+}
+
+// ObjectIDFromTime returns an ObjectID with the given time and all other bytes zeroed.
+// Useful for range queries on _id.
+func ObjectIDFromTime(t time.Time) ObjectID {
 	ts := uint32(t.UTC().Unix())
-	c := objectIDCounter.Add(1)
 
 	var oid ObjectID
 	oid[0] = byte(ts >> 24)
 	oid[1] = byte(ts >> 16)
 	oid[2] = byte(ts >> 8)
 	oid[3] = byte(ts)
-
-	oid[4] = procUniqueID[0]
-	oid[5] = procUniqueID[1]
-	oid[6] = procUniqueID[2]
-	oid[7] = procUniqueID[3]
-	oid[8] = procUniqueID[4]
-
-	oid[9] = byte(c >> 16)
-	oid[10] = byte(c >> 8)
-	oid[11] = byte(c)
 	return oid
 }
 
+// ObjectIDFromHex creates a new ObjectID from a hex string.
+func ObjectIDFromHex(s string) (ObjectID, error) {
+	var oid ObjectID
+	if len(s) != 24 {
+		return NilObjectID, ErrBadObjectID
+	}
+	if _, err := hex.Decode(oid[:], []byte(s)); err != nil {
+		return NilObjectID, ErrBadObjectID
+	}
+	return oid, nil
+}
+
 // String returns a hex string representation of the id.
 // Example: ObjectID('64d526fa37931c1e97eea90f').
 func (oid ObjectID) String() string {
-	return "ObjectID('" + hex.EncodeToString(oid[:]) + "')"
+	return "ObjectID('" + oid.Hex() + "')"
+}
+
+// Hex returns a hex encoding of the id.
+// Example: 64d526fa37931c1e97eea90f.
+func (oid ObjectID) Hex() string {
+	return hex.EncodeToString(oid[:])
+}
+
+// Timestamp returns the time part of the id.
+func (oid ObjectID) Timestamp() time.Time {
+	ts := uint32(oid[0])<<24 |
+		uint32(oid[1])<<16 |
+		uint32(oid[2])<<8 |
+		uint32(oid[3])
+	return time.Unix(int64(ts), 0).UTC()
+}
+
+// IsZero reports whether the id is [NilObjectID].
+func (oid ObjectID) IsZero() bool {
+	return oid == NilObjectID
+}
+
+// Compare returns an integer comparing two ids.
+// The result will be 0 if oid == other, -1 if oid < other, and +1 if oid > other.
+func (oid ObjectID) Compare(other ObjectID) int {
+	return bytes.Compare(oid[:], other[:])
 }
 
 // MarshalBSON implements [bson.Marshaler].
@@ -64,11 +172,12 @@ func (oid *ObjectID) UnmarshalBSON(b []byte) error {
 		copy(oid[:], b)
 		return nil
 	case 24:
-		n, err := hex.Decode(oid[:], b)
-		if n != 12 {
-			panic("unreachable")
+		id, err := ObjectIDFromHex(string(b))
+		if err != nil {
+			return err
 		}
-		return err
+		*oid = id
+		return nil
 	default:
 		return ErrBadObjectID
 	}
@@ -105,13 +214,12 @@ func (oid *ObjectID) UnmarshalJSON(b []byte) error {
 }
 
 var (
-	procUniqueID    [5]byte
-	objectIDCounter atomic.Uint32
+	globalObjectIDGenerator  = NewObjectIDGenerator()
+	defaultObjectIDGenerator atomic.Pointer[ObjectIDGenerator]
 )
 
 func init() {
-	must(rand.Read(procUniqueID[:]))
-	objectIDCounter.Store(mathrand.Uint32())
+	defaultObjectIDGenerator.Store(globalObjectIDGenerator)
 }
 
 var (
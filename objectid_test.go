@@ -11,7 +11,7 @@ func TestObjectID(t *testing.T) {
 	t.Run("NewObjectID", func(t *testing.T) {
 		now := time.Now()
 		id := NewObjectID()
-		counter := objectIDCounter.Load()
+		counter := globalObjectIDGenerator.counter.Load()
 		b := [12]byte(id)
 		ts := uint32(now.UTC().Unix())
 
@@ -20,17 +20,84 @@ func TestObjectID(t *testing.T) {
 		mustEqual(t, b[2], byte(ts>>8))
 		mustEqual(t, b[3], byte(ts>>0))
 
-		mustEqual(t, b[4], procUniqueID[0])
-		mustEqual(t, b[5], procUniqueID[1])
-		mustEqual(t, b[6], procUniqueID[2])
-		mustEqual(t, b[7], procUniqueID[3])
-		mustEqual(t, b[8], procUniqueID[4])
+		mustEqual(t, b[4], globalObjectIDGenerator.ProcessID[0])
+		mustEqual(t, b[5], globalObjectIDGenerator.ProcessID[1])
+		mustEqual(t, b[6], globalObjectIDGenerator.ProcessID[2])
+		mustEqual(t, b[7], globalObjectIDGenerator.ProcessID[3])
+		mustEqual(t, b[8], globalObjectIDGenerator.ProcessID[4])
 
 		mustEqual(t, b[9], byte(counter>>16))
 		mustEqual(t, b[10], byte(counter>>8))
 		mustEqual(t, b[11], byte(counter>>0))
 	})
 
+	t.Run("Generator", func(t *testing.T) {
+		now := time.Unix(1691690746, 0)
+		gen := &ObjectIDGenerator{
+			Now:       func() time.Time { return now },
+			ProcessID: [5]byte{1, 2, 3, 4, 5},
+		}
+
+		wantBytes(t, must(gen.New().MarshalBinary()), "64d526fa0102030405000001")
+		wantBytes(t, must(gen.New().MarshalBinary()), "64d526fa0102030405000002")
+
+		gen.Counter = func() uint32 { return 0xaabbccdd }
+		wantBytes(t, must(gen.New().MarshalBinary()), "64d526fa0102030405bbccdd")
+	})
+
+	t.Run("SetObjectIDGenerator", func(t *testing.T) {
+		gen := &ObjectIDGenerator{
+			Now: func() time.Time { return time.Unix(1691690746, 0) },
+		}
+
+		prev := SetObjectIDGenerator(gen)
+		defer SetObjectIDGenerator(prev)
+
+		wantBytes(t, must(NewObjectID().MarshalBinary()), "64d526fa0000000000000001")
+		wantBytes(t, must(NewObjectID().MarshalBinary()), "64d526fa0000000000000002")
+
+		SetObjectIDGenerator(nil)
+		id := NewObjectID()
+		mustEqual(t, id[4], globalObjectIDGenerator.ProcessID[0])
+	})
+
+	t.Run("FromTime", func(t *testing.T) {
+		now := time.Unix(1691690746, 0)
+		id := ObjectIDFromTime(now)
+
+		wantBytes(t, id[:], "64d526fa0000000000000000")
+		mustEqual(t, id.Timestamp(), now.UTC())
+		mustEqual(t, id.Timestamp(), NewObjectIDWithTime(now).Timestamp())
+	})
+
+	t.Run("FromHex", func(t *testing.T) {
+		id, err := ObjectIDFromHex("0102030405060708090a0b0c")
+		mustOk(t, err)
+		mustEqual(t, id, oid)
+		mustEqual(t, id.Hex(), "0102030405060708090a0b0c")
+
+		_, err = ObjectIDFromHex("0102030405060708090a0b")
+		mustFail(t, err)
+		_, err = ObjectIDFromHex("0102030405060708090a0b0z")
+		mustFail(t, err)
+	})
+
+	t.Run("IsZero", func(t *testing.T) {
+		mustEqual(t, NilObjectID.IsZero(), true)
+		mustEqual(t, ObjectID{}.IsZero(), true)
+		mustEqual(t, oid.IsZero(), false)
+	})
+
+	t.Run("Compare", func(t *testing.T) {
+		lo := ObjectIDFromTime(time.Unix(1000, 0))
+		hi := ObjectIDFromTime(time.Unix(2000, 0))
+
+		mustEqual(t, lo.Compare(hi), -1)
+		mustEqual(t, hi.Compare(lo), 1)
+		mustEqual(t, lo.Compare(lo), 0)
+		mustEqual(t, NilObjectID.Compare(oid), -1)
+	})
+
 	t.Run("Marshal", func(t *testing.T) {
 		mustEqual(t, oid.String(), "ObjectID('0102030405060708090a0b0c')")
 		wantBytes(t, must(oid.MarshalText()), "0102030405060708090a0b0c")
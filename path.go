@@ -0,0 +1,498 @@
+package bson
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// GetPath returns the value at the given dot-notation path.
+// Path elements are keys for documents and indices for arrays.
+//
+// Supported containers are D, M, A, []any, map[string]any,
+// maps with string keys, slices, arrays and structs (keys are taken from bson tags).
+//
+// Example:
+//
+//	city, err := bson.GetPath(doc, "address.city")
+//	sku, err := bson.GetPath(doc, "items.3.sku")
+func GetPath(doc any, path string) (any, error) {
+	keys, err := splitPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	v := doc
+	for i, key := range keys {
+		child, ok, err := getKey(v, key)
+		if err != nil {
+			return nil, pathError(keys[:i], err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrElementNotFound, strings.Join(keys[:i+1], "."))
+		}
+		v = child
+	}
+	return v, nil
+}
+
+// SetPath sets the value at the given dot-notation path.
+// Missing intermediate documents are created, arrays are padded with nulls when needed,
+// padding by more than 1500000 elements returns an error as MongoDB does.
+//
+// doc must be a map or a pointer to a container (like *D, *A or *struct).
+func SetPath(doc any, path string, value any) error {
+	keys, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+	return updateRoot(doc, func(root any) (any, error) {
+		return setPath(root, keys, 0, value)
+	})
+}
+
+// UnsetPath removes the element at the given dot-notation path.
+// Array elements are set to null to keep positions of the others, struct fields are zeroed.
+// Missing elements are ignored.
+//
+// doc must be a map or a pointer to a container (like *D, *A or *struct).
+func UnsetPath(doc any, path string) error {
+	keys, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+	return updateRoot(doc, func(root any) (any, error) {
+		return unsetPath(root, keys, 0)
+	})
+}
+
+func splitPath(path string) ([]string, error) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys {
+		if key == "" {
+			return nil, fmt.Errorf("invalid path %q: empty element", path)
+		}
+	}
+	return keys, nil
+}
+
+func updateRoot(doc any, update func(root any) (any, error)) error {
+	rv := reflect.ValueOf(doc)
+	switch {
+	case rv.Kind() == reflect.Map && !rv.IsNil():
+		_, err := update(doc)
+		return err
+
+	case rv.Kind() == reflect.Ptr && !rv.IsNil():
+		if rv.Elem().Kind() == reflect.Struct {
+			_, err := update(doc)
+			return err
+		}
+
+		res, err := update(rv.Elem().Interface())
+		if err != nil {
+			return err
+		}
+		val, err := convertValue(res, rv.Elem().Type())
+		if err != nil {
+			return err
+		}
+		rv.Elem().Set(val)
+		return nil
+
+	default:
+		return fmt.Errorf("path update requires a non-nil map or pointer, got %T", doc)
+	}
+}
+
+func setPath(v any, keys []string, i int, value any) (any, error) {
+	key := keys[i]
+	if i == len(keys)-1 {
+		res, err := setKey(v, key, value)
+		if err != nil {
+			return nil, pathError(keys[:i], err)
+		}
+		return res, nil
+	}
+
+	child, ok, err := getKey(v, key)
+	if err != nil {
+		return nil, pathError(keys[:i], err)
+	}
+	if !ok || isNilValue(child) {
+		child = newDocLike(v, key)
+	}
+
+	child, err = setPath(child, keys, i+1, value)
+	if err != nil {
+		return nil, err
+	}
+	res, err := setKey(v, key, child)
+	if err != nil {
+		return nil, pathError(keys[:i], err)
+	}
+	return res, nil
+}
+
+func unsetPath(v any, keys []string, i int) (any, error) {
+	key := keys[i]
+	if i == len(keys)-1 {
+		res, err := unsetKey(v, key)
+		if err != nil {
+			return nil, pathError(keys[:i], err)
+		}
+		return res, nil
+	}
+
+	child, ok, err := getKey(v, key)
+	switch {
+	case err != nil:
+		return nil, pathError(keys[:i], err)
+	case !ok || isNilValue(child):
+		return v, nil
+	}
+
+	child, err = unsetPath(child, keys, i+1)
+	if err != nil {
+		return nil, err
+	}
+	res, err := setKey(v, key, child)
+	if err != nil {
+		return nil, pathError(keys[:i], err)
+	}
+	return res, nil
+}
+
+// getKey returns a child of the container v.
+func getKey(v any, key string) (any, bool, error) {
+	switch v := v.(type) {
+	case D:
+		val, ok := v.Get(key)
+		return val, ok, nil
+	case M:
+		val, ok := v[key]
+		return val, ok, nil
+	case map[string]any:
+		val, ok := v[key]
+		return val, ok, nil
+	case A:
+		return getIndex(v, key)
+	case []any:
+		return getIndex(v, key)
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, false, nil
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		field, ok := structField(rv, key)
+		if !ok {
+			return nil, false, nil
+		}
+		return field.Interface(), true, nil
+
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, false, scalarError(v)
+		}
+		val := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+		if !val.IsValid() {
+			return nil, false, nil
+		}
+		return val.Interface(), true, nil
+
+	case reflect.Slice, reflect.Array:
+		idx, ok := parseIndex(key)
+		if !ok || idx >= rv.Len() {
+			return nil, false, nil
+		}
+		return rv.Index(idx).Interface(), true, nil
+
+	default:
+		return nil, false, scalarError(v)
+	}
+}
+
+// setKey sets a child of the container v and returns the updated container.
+func setKey(v any, key string, value any) (any, error) {
+	switch v := v.(type) {
+	case D:
+		v.Set(key, value)
+		return v, nil
+	case M:
+		v[key] = value
+		return v, nil
+	case map[string]any:
+		v[key] = value
+		return v, nil
+	case A:
+		return setIndex(v, key, value)
+	case []any:
+		return setIndex(v, key, value)
+	}
+
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return nil, scalarError(v)
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+			return nil, scalarError(v)
+		}
+		if err := setStructField(rv.Elem(), key, value); err != nil {
+			return nil, err
+		}
+		return v, nil
+
+	case reflect.Struct:
+		// not addressable, update a copy.
+		cp := reflect.New(rv.Type()).Elem()
+		cp.Set(rv)
+		if err := setStructField(cp, key, value); err != nil {
+			return nil, err
+		}
+		return cp.Interface(), nil
+
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, scalarError(v)
+		}
+		val, err := convertValue(value, rv.Type().Elem())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if rv.IsNil() {
+			rv = reflect.MakeMap(rv.Type())
+		}
+		rv.SetMapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()), val)
+		return rv.Interface(), nil
+
+	case reflect.Slice:
+		idx, ok := parseIndex(key)
+		if !ok {
+			return nil, fmt.Errorf("%q is not an array index", key)
+		}
+		val, err := convertValue(value, rv.Type().Elem())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if err := checkPadding(rv.Len(), idx); err != nil {
+			return nil, err
+		}
+		for rv.Len() <= idx {
+			rv = reflect.Append(rv, reflect.Zero(rv.Type().Elem()))
+		}
+		rv.Index(idx).Set(val)
+		return rv.Interface(), nil
+
+	default:
+		return nil, scalarError(v)
+	}
+}
+
+// unsetKey removes a child of the container v and returns the updated container.
+func unsetKey(v any, key string) (any, error) {
+	switch v := v.(type) {
+	case D:
+		v.Delete(key)
+		return v, nil
+	case M:
+		delete(v, key)
+		return v, nil
+	case map[string]any:
+		delete(v, key)
+		return v, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String {
+		rv.SetMapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()), reflect.Value{})
+		return v, nil
+	}
+
+	if _, ok, err := getKey(v, key); err != nil || !ok {
+		return v, err
+	}
+	return setKey(v, key, nil)
+}
+
+func getIndex(a []any, key string) (any, bool, error) {
+	idx, ok := parseIndex(key)
+	if !ok || idx >= len(a) {
+		return nil, false, nil
+	}
+	return a[idx], true, nil
+}
+
+func setIndex[S ~[]any](a S, key string, value any) (S, error) {
+	idx, ok := parseIndex(key)
+	if !ok {
+		return nil, fmt.Errorf("%q is not an array index", key)
+	}
+	if err := checkPadding(len(a), idx); err != nil {
+		return nil, err
+	}
+	for len(a) <= idx {
+		a = append(a, nil)
+	}
+	a[idx] = value
+	return a, nil
+}
+
+// maxPadding is the maximum number of elements appended to an array
+// to set an index past its end, the same limit as in MongoDB.
+const maxPadding = 1500000
+
+func checkPadding(length, idx int) error {
+	if idx-length > maxPadding {
+		return fmt.Errorf("index %d: cannot pad array of length %d by more than %d elements", idx, length, maxPadding)
+	}
+	return nil
+}
+
+func parseIndex(key string) (int, bool) {
+	idx, err := strconv.Atoi(key)
+	if err != nil || idx < 0 || strconv.Itoa(idx) != key {
+		return 0, false
+	}
+	return idx, true
+}
+
+func structField(v reflect.Value, key string) (reflect.Value, bool) {
+	info, err := getStruct(v)
+	if err != nil {
+		return reflect.Value{}, false
+	}
+	field, ok := info.field(key)
+	if !ok {
+		return reflect.Value{}, false
+	}
+	return v.Field(field.Num), true
+}
+
+func setStructField(v reflect.Value, key string, value any) error {
+	field, ok := structField(v, key)
+	if !ok {
+		return fmt.Errorf("%s: no such field in %v", key, v.Type())
+	}
+
+	val, err := convertValue(value, field.Type())
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	field.Set(val)
+	return nil
+}
+
+// newDocLike returns an empty document to be stored in v by the key.
+func newDocLike(v any, key string) any {
+	switch v.(type) {
+	case M:
+		return M{}
+	case map[string]any:
+		return map[string]any{}
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Struct {
+		if field, ok := structField(rv, key); ok {
+			switch typ := field.Type(); typ.Kind() {
+			case reflect.Ptr:
+				return reflect.New(typ.Elem()).Interface()
+			case reflect.Map:
+				return reflect.MakeMap(typ).Interface()
+			case reflect.Struct, reflect.Slice:
+				return reflect.Zero(typ).Interface()
+			}
+		}
+	}
+	return D{}
+}
+
+// convertValue returns v as a value of the type t.
+// Only assignable values and conversions between numbers are allowed.
+func convertValue(v any, t reflect.Type) (reflect.Value, error) {
+	if v == nil {
+		return reflect.Zero(t), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.Type().AssignableTo(t):
+		return rv, nil
+	case rv.Type().ConvertibleTo(t) && rv.Kind() == t.Kind():
+		return rv.Convert(t), nil
+	case rv.Type().ConvertibleTo(t) && isNumberKind(rv.Kind()) && isNumberKind(t.Kind()):
+		res := rv.Convert(t)
+		if !isExactNumber(rv, res) {
+			return reflect.Value{}, fmt.Errorf("%w: %v overflows or loses precision in %v", ErrTypeMismatch, v, t)
+		}
+		return res, nil
+	default:
+		return reflect.Value{}, fmt.Errorf("%w: cannot assign %T to %v", ErrTypeMismatch, v, t)
+	}
+}
+
+// isExactNumber reports whether the number res converted from v holds the same value.
+// Floats can lose precision between float64 and float32 but must not overflow.
+func isExactNumber(v, res reflect.Value) bool {
+	isFloat := func(k reflect.Kind) bool { return k == reflect.Float32 || k == reflect.Float64 }
+	if isFloat(v.Kind()) && isFloat(res.Kind()) {
+		f := v.Float()
+		return math.IsNaN(f) || math.IsInf(f, 0) || !res.OverflowFloat(f)
+	}
+	if isFloat(v.Kind()) {
+		f := v.Float()
+		if f != math.Trunc(f) || math.IsInf(f, 0) || (f < 0 && isUnsigned(res.Kind())) {
+			return false
+		}
+	}
+	if (isSigned(v.Kind()) && v.Int() < 0 && isUnsigned(res.Kind())) ||
+		(isUnsigned(v.Kind()) && isSigned(res.Kind()) && res.Int() < 0) {
+		return false
+	}
+	return res.Convert(v.Type()).Interface() == v.Interface()
+}
+
+func isSigned(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUnsigned(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+func isNilValue(v any) bool {
+	if v == nil {
+		return true
+	}
+	return isNil(reflect.ValueOf(v))
+}
+
+// ErrNotContainer is returned when a path traverses a value that is not a document or array.
+var ErrNotContainer = errors.New("element is not a document or array")
+
+func scalarError(v any) error {
+	return fmt.Errorf("%w: %T", ErrNotContainer, v)
+}
+
+func pathError(keys []string, err error) error {
+	if len(keys) == 0 {
+		return err
+	}
+	return fmt.Errorf("%s: %w", strings.Join(keys, "."), err)
+}
@@ -0,0 +1,205 @@
+package bson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetPath(t *testing.T) {
+	type address struct {
+		City string `bson:"city"`
+		Zip  int
+	}
+	type user struct {
+		Name    string   `bson:"name"`
+		Address *address `bson:"address"`
+		Tags    []string `bson:"tags"`
+	}
+
+	doc := D{
+		{"name", "john"},
+		{"address", M{"city": "Berlin", "geo": map[string]any{"lat": 52.5}}},
+		{"items", A{
+			D{{"sku", "a-1"}},
+			[]any{int32(1), int32(2)},
+		}},
+		{"user", user{
+			Name:    "jane",
+			Address: &address{City: "Paris", Zip: 75001},
+			Tags:    []string{"x", "y"},
+		}},
+	}
+
+	testCases := []struct {
+		path string
+		want any
+	}{
+		{"name", "john"},
+		{"address.city", "Berlin"},
+		{"address.geo.lat", 52.5},
+		{"items.0.sku", "a-1"},
+		{"items.1.1", int32(2)},
+		{"user.name", "jane"},
+		{"user.address.city", "Paris"},
+		{"user.address.zip", 75001},
+		{"user.tags.1", "y"},
+	}
+
+	for _, tc := range testCases {
+		got, err := GetPath(doc, tc.path)
+		mustOk(t, err)
+		mustDeepEqual(t, got, tc.want)
+	}
+
+	_, err := GetPath(doc, "address.street")
+	mustEqual(t, errors.Is(err, ErrElementNotFound), true)
+	mustEqual(t, err.Error(), "element not found: address.street")
+
+	_, err = GetPath(doc, "items.5.sku")
+	mustEqual(t, errors.Is(err, ErrElementNotFound), true)
+
+	_, err = GetPath(doc, "items.01")
+	mustEqual(t, errors.Is(err, ErrElementNotFound), true)
+
+	_, err = GetPath(doc, "name.first")
+	mustEqual(t, errors.Is(err, ErrNotContainer), true)
+	mustEqual(t, err.Error(), "name: element is not a document or array: string")
+
+	_, err = GetPath(doc, "a..b")
+	mustFail(t, err)
+}
+
+func TestSetPath(t *testing.T) {
+	t.Run("D", func(t *testing.T) {
+		doc := D{{"a", int32(1)}, {"b", D{{"c", "x"}}}}
+
+		mustOk(t, SetPath(&doc, "b.c", "y"))
+		mustOk(t, SetPath(&doc, "b.d", int32(2)))
+		mustOk(t, SetPath(&doc, "e.f.g", true))
+		mustOk(t, SetPath(&doc, "a", int64(5)))
+
+		mustDeepEqual(t, doc, D{
+			{"a", int64(5)},
+			{"b", D{{"c", "y"}, {"d", int32(2)}}},
+			{"e", D{{"f", D{{"g", true}}}}},
+		})
+	})
+
+	t.Run("M", func(t *testing.T) {
+		doc := M{"a": map[string]any{"b": int32(1)}}
+
+		mustOk(t, SetPath(doc, "a.b", int32(2)))
+		mustOk(t, SetPath(doc, "x.y", "z"))
+
+		mustDeepEqual(t, doc, M{
+			"a": map[string]any{"b": int32(2)},
+			"x": M{"y": "z"},
+		})
+	})
+
+	t.Run("Array", func(t *testing.T) {
+		doc := D{{"items", A{D{{"sku", "a"}}}}}
+
+		mustOk(t, SetPath(&doc, "items.0.sku", "b"))
+		mustOk(t, SetPath(&doc, "items.2", "c"))
+
+		mustDeepEqual(t, doc, D{{"items", A{D{{"sku", "b"}}, nil, "c"}}})
+
+		arr := A{}
+		mustOk(t, SetPath(&arr, "1.x", int32(1)))
+		mustDeepEqual(t, arr, A{nil, D{{"x", int32(1)}}})
+	})
+
+	t.Run("Struct", func(t *testing.T) {
+		type address struct {
+			City string `bson:"city"`
+		}
+		type user struct {
+			Name    string            `bson:"name"`
+			Age     int64             `bson:"age"`
+			Address *address          `bson:"address"`
+			Extra   map[string]string `bson:"extra"`
+			Tags    []string          `bson:"tags"`
+		}
+
+		var u user
+		mustOk(t, SetPath(&u, "name", "john"))
+		mustOk(t, SetPath(&u, "age", int32(42)))
+		mustOk(t, SetPath(&u, "address.city", "Berlin"))
+		mustOk(t, SetPath(&u, "extra.k", "v"))
+		mustOk(t, SetPath(&u, "tags.1", "b"))
+
+		mustEqual(t, u.Name, "john")
+		mustEqual(t, u.Age, int64(42))
+		mustEqual(t, u.Address.City, "Berlin")
+		mustEqual(t, u.Extra["k"], "v")
+		mustDeepEqual(t, u.Tags, []string{"", "b"})
+
+		doc := D{{"user", user{Name: "jane"}}}
+		mustOk(t, SetPath(&doc, "user.name", "joe"))
+		mustEqual(t, doc[0].Value.(user).Name, "joe")
+
+		mustFail(t, SetPath(&u, "name", 1))
+		mustEqual(t, errors.Is(SetPath(&u, "age", 3.7), ErrTypeMismatch), true)
+		mustEqual(t, u.Age, int64(42))
+		mustOk(t, SetPath(&u, "age", 7.0))
+		mustEqual(t, u.Age, int64(7))
+		mustFail(t, SetPath(&u, "unknown", 1))
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		doc := D{{"a", "str"}}
+
+		err := SetPath(&doc, "a.b", 1)
+		mustEqual(t, errors.Is(err, ErrNotContainer), true)
+		mustEqual(t, err.Error(), "a: element is not a document or array: string")
+
+		mustFail(t, SetPath(doc, "a", 1))
+		mustFail(t, SetPath(&doc, "", 1))
+
+		arr := A{}
+		mustFail(t, SetPath(&arr, "x", 1))
+
+		// arrays are not padded without a limit.
+		items := D{{"items", A{}}}
+		mustFail(t, SetPath(&items, "items.999999999", 1))
+		mustEqual(t, len(items[0].Value.(A)), 0)
+		tags := struct{ Tags []string }{}
+		mustFail(t, SetPath(&tags, "tags.999999999", "x"))
+		mustEqual(t, len(tags.Tags), 0)
+		mustOk(t, SetPath(&arr, "1500000", 1))
+		mustEqual(t, len(arr), 1500001)
+	})
+}
+
+func TestUnsetPath(t *testing.T) {
+	doc := D{
+		{"a", int32(1)},
+		{"b", D{{"c", "x"}, {"d", "y"}}},
+		{"items", A{"p", "q", "r"}},
+		{"m", M{"k": "v"}},
+	}
+
+	mustOk(t, UnsetPath(&doc, "a"))
+	mustOk(t, UnsetPath(&doc, "b.c"))
+	mustOk(t, UnsetPath(&doc, "items.1"))
+	mustOk(t, UnsetPath(&doc, "m.k"))
+	mustOk(t, UnsetPath(&doc, "x.y.z"))
+	mustOk(t, UnsetPath(&doc, "items.9"))
+
+	mustDeepEqual(t, doc, D{
+		{"b", D{{"d", "y"}}},
+		{"items", A{"p", nil, "r"}},
+		{"m", M{}},
+	})
+
+	type user struct {
+		Name string `bson:"name"`
+	}
+	u := user{Name: "john"}
+	mustOk(t, UnsetPath(&u, "name"))
+	mustEqual(t, u.Name, "")
+
+	err := UnsetPath(&doc, "b.d.e")
+	mustEqual(t, errors.Is(err, ErrNotContainer), true)
+}
@@ -0,0 +1,287 @@
+package bson
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Project returns a new document with fields selected by the projection.
+// The order of fields in the result follows the order of the input document.
+//
+// Projection is either an inclusion ({"a": 1, "b.c": 1}) or an exclusion ({"a": 0, "b.c": 0}),
+// the only field that can be excluded in an inclusion projection is _id.
+// _id is included by default.
+//
+// Supported projection operators:
+//
+//	{"tags": {"$slice": 5}}                        - first 5 elements, negative values are counted from the end
+//	{"tags": {"$slice": [10, 5]}}                  - 5 elements after skipping 10
+//	{"items": {"$elemMatch": {"qty": {"$gt": 1}}}} - the first matching element only
+//
+// doc can be a D, M, RawObject or any other value representing a document.
+func Project(doc any, projection D) (D, error) {
+	if raw, ok := doc.(RawObject); ok {
+		d, err := rawToD(raw, 1)
+		if err != nil {
+			return nil, err
+		}
+		doc = d
+	}
+
+	d, ok := asDocument(doc)
+	if !ok {
+		return nil, fmt.Errorf("cannot project %T: not a document", doc)
+	}
+
+	p, err := compileProjection(projection)
+	if err != nil {
+		return nil, err
+	}
+	return p.projectDoc(d), nil
+}
+
+// projection is a node of a compiled projection tree.
+type projection struct {
+	include   bool
+	fields    map[string]*projection
+	slice     *[2]int // skip and limit, negative skip is counted from the end.
+	elemMatch func(elem any) bool
+}
+
+func compileProjection(spec D) (*projection, error) {
+	root := &projection{fields: map[string]*projection{}}
+
+	inclusion, exclusion := false, false
+	idSet := false
+
+	for _, elem := range spec {
+		keys, err := splitPath(elem.Key)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(keys[0], "$") {
+			return nil, fmt.Errorf("invalid projection field %q", elem.Key)
+		}
+
+		node := &projection{}
+		if op, ok := asDocument(elem.Value); ok {
+			if err := node.compileOperator(elem.Key, op, len(keys) > 1); err != nil {
+				return nil, err
+			}
+			if node.elemMatch != nil {
+				inclusion = true
+			}
+		} else {
+			if _, ok := asNumber(elem.Value); !ok && !isBool(elem.Value) {
+				return nil, fmt.Errorf("%s: projection value must be a number, bool or document, got %T", elem.Key, elem.Value)
+			}
+			node.include = isTruthy(elem.Value)
+
+			switch {
+			case elem.Key == "_id":
+				idSet = true
+			case node.include:
+				inclusion = true
+			default:
+				exclusion = true
+			}
+		}
+
+		if inclusion && exclusion {
+			return nil, fmt.Errorf("%s: cannot mix inclusion and exclusion in a projection", elem.Key)
+		}
+		if err := root.insert(keys, node); err != nil {
+			return nil, fmt.Errorf("%s: %w", elem.Key, err)
+		}
+	}
+
+	if !idSet && inclusion {
+		root.fields["_id"] = &projection{include: true}
+	}
+	if idSet && !inclusion && !exclusion && root.fields["_id"].include {
+		// {_id: 1} alone is an inclusion projection.
+		inclusion = true
+	}
+	root.setMode(inclusion)
+	return root, nil
+}
+
+func (p *projection) compileOperator(path string, op D, nested bool) error {
+	if len(op) != 1 {
+		return fmt.Errorf("%s: projection operator document must have exactly one field", path)
+	}
+
+	switch elem := op[0]; elem.Key {
+	case "$slice":
+		if n, ok := asInt(elem.Value); ok {
+			if n < 0 {
+				p.slice = &[2]int{n, -n}
+			} else {
+				p.slice = &[2]int{0, n}
+			}
+			return nil
+		}
+		arr, ok := asArray(elem.Value)
+		if !ok || len(arr) != 2 {
+			return fmt.Errorf("%s: $slice must be a number or an array of 2 numbers", path)
+		}
+		skip, ok1 := asInt(arr[0])
+		limit, ok2 := asInt(arr[1])
+		if !ok1 || !ok2 || limit <= 0 {
+			return fmt.Errorf("%s: $slice must be [skip, limit] with a positive limit", path)
+		}
+		p.slice = &[2]int{skip, limit}
+		return nil
+
+	case "$elemMatch":
+		if nested {
+			return fmt.Errorf("%s: $elemMatch cannot be used on nested fields", path)
+		}
+		cond, ok := asDocument(elem.Value)
+		if !ok {
+			return errors.New("$elemMatch needs a document")
+		}
+		m, err := compileElemMatch(cond)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		p.elemMatch = m
+		p.include = true
+		return nil
+
+	default:
+		return fmt.Errorf("%s: unsupported projection operator %s", path, elem.Key)
+	}
+}
+
+func (p *projection) insert(keys []string, node *projection) error {
+	cur := p
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := cur.fields[key]
+		if !ok {
+			next = &projection{fields: map[string]*projection{}}
+			cur.fields[key] = next
+		}
+		if next.fields == nil {
+			return errors.New("path collision")
+		}
+		cur = next
+	}
+
+	last := keys[len(keys)-1]
+	if _, ok := cur.fields[last]; ok {
+		return errors.New("path collision")
+	}
+	cur.fields[last] = node
+	return nil
+}
+
+// setMode marks intermediate nodes with the projection mode,
+// it decides what happens with fields which are not mentioned in the projection.
+func (p *projection) setMode(inclusion bool) {
+	if p.fields == nil {
+		return
+	}
+	p.include = inclusion
+	for _, child := range p.fields {
+		child.setMode(inclusion)
+	}
+}
+
+// projectDoc applies the projection to the document.
+// In inclusion mode missing fields are dropped, in exclusion mode they are kept.
+func (p *projection) projectDoc(doc D) D {
+	res := D{}
+	for _, elem := range doc {
+		node, ok := p.fields[elem.Key]
+		if !ok {
+			if !p.include {
+				res = append(res, E{Key: elem.Key, Value: elem.Value})
+			}
+			continue
+		}
+
+		if v, ok := node.projectValue(elem.Value); ok {
+			res = append(res, E{Key: elem.Key, Value: v})
+		}
+	}
+	return res
+}
+
+// projectValue applies the projection node to the value, reports whether the value is kept.
+func (p *projection) projectValue(v any) (any, bool) {
+	switch {
+	case p.elemMatch != nil:
+		arr, ok := asArray(v)
+		if !ok {
+			return nil, false
+		}
+		for _, elem := range arr {
+			if p.elemMatch(elem) {
+				return A{elem}, true
+			}
+		}
+		return nil, false
+
+	case p.slice != nil:
+		arr, ok := asArray(v)
+		if !ok {
+			return v, true
+		}
+		return sliceArray(arr, p.slice[0], p.slice[1]), true
+
+	case p.fields != nil:
+		if d, ok := asDocument(v); ok {
+			return p.projectDoc(d), true
+		}
+		if arr, ok := asArray(v); ok {
+			res := A{}
+			for _, elem := range arr {
+				if val, ok := p.projectElem(elem); ok {
+					res = append(res, val)
+				}
+			}
+			return res, true
+		}
+		// scalars are dropped by inclusion and kept by exclusion.
+		return v, !p.include
+
+	default:
+		return v, p.include
+	}
+}
+
+// projectElem applies the projection to an array element,
+// nested arrays are traversed and scalars are dropped by inclusion.
+func (p *projection) projectElem(v any) (any, bool) {
+	if _, ok := asDocument(v); ok {
+		return p.projectValue(v)
+	}
+	if _, ok := asArray(v); ok {
+		return p.projectValue(v)
+	}
+	return v, !p.include
+}
+
+func sliceArray(arr A, skip, limit int) A {
+	if skip < 0 {
+		skip += len(arr)
+		if skip < 0 {
+			skip = 0
+		}
+	}
+	if skip > len(arr) {
+		skip = len(arr)
+	}
+	end := skip + limit
+	if end > len(arr) {
+		end = len(arr)
+	}
+	return append(A{}, arr[skip:end]...)
+}
+
+func isBool(v any) bool {
+	_, ok := v.(bool)
+	return ok
+}
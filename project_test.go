@@ -0,0 +1,137 @@
+package bson
+
+import "testing"
+
+func TestProject(t *testing.T) {
+	doc := D{
+		{"_id", int32(1)},
+		{"name", "john"},
+		{"age", int32(42)},
+		{"address", D{{"city", "Berlin"}, {"zip", "10115"}}},
+		{"tags", A{"a", "b", "c", "d", "e"}},
+		{"items", A{
+			D{{"sku", "x"}, {"qty", int32(2)}},
+			D{{"sku", "y"}, {"qty", int32(10)}},
+			"scalar",
+		}},
+	}
+
+	testCases := []struct {
+		projection D
+		want       D
+	}{
+		{D{}, doc},
+		{
+			D{{"age", int32(1)}, {"name", true}},
+			D{{"_id", int32(1)}, {"name", "john"}, {"age", int32(42)}},
+		},
+		{
+			D{{"name", int32(1)}, {"_id", int32(0)}},
+			D{{"name", "john"}},
+		},
+		{
+			D{{"_id", int32(0)}},
+			doc[1:],
+		},
+		{
+			D{{"_id", int32(1)}},
+			D{{"_id", int32(1)}},
+		},
+		{
+			D{{"_id", true}},
+			D{{"_id", int32(1)}},
+		},
+		{
+			D{{"address", int32(0)}, {"tags", int32(0)}, {"items", int32(0)}},
+			D{{"_id", int32(1)}, {"name", "john"}, {"age", int32(42)}},
+		},
+		{
+			D{{"address.city", int32(1)}, {"_id", false}},
+			D{{"address", D{{"city", "Berlin"}}}},
+		},
+		{
+			D{{"address.zip", int32(0)}, {"tags", int32(0)}, {"items", int32(0)}},
+			D{{"_id", int32(1)}, {"name", "john"}, {"age", int32(42)}, {"address", D{{"city", "Berlin"}}}},
+		},
+		{
+			D{{"items.sku", int32(1)}, {"_id", int32(0)}},
+			D{{"items", A{D{{"sku", "x"}}, D{{"sku", "y"}}}}},
+		},
+		{
+			D{{"items.qty", int32(0)}, {"_id", int32(0)}, {"name", int32(0)}, {"age", int32(0)}, {"address", int32(0)}, {"tags", int32(0)}},
+			D{{"items", A{D{{"sku", "x"}}, D{{"sku", "y"}}, "scalar"}}},
+		},
+		{
+			D{{"name.first", int32(1)}, {"_id", int32(0)}},
+			D{},
+		},
+		{
+			D{{"tags", D{{"$slice", int32(2)}}}, {"items", int32(0)}, {"address", int32(0)}},
+			D{{"_id", int32(1)}, {"name", "john"}, {"age", int32(42)}, {"tags", A{"a", "b"}}},
+		},
+		{
+			D{{"name", int32(1)}, {"tags", D{{"$slice", int32(-2)}}}},
+			D{{"_id", int32(1)}, {"name", "john"}, {"tags", A{"d", "e"}}},
+		},
+		{
+			D{{"tags", D{{"$slice", A{int32(1), int32(2)}}}}, {"_id", int32(0)}, {"name", int32(1)}},
+			D{{"name", "john"}, {"tags", A{"b", "c"}}},
+		},
+		{
+			D{{"tags", D{{"$slice", A{int32(-1), int32(5)}}}}, {"name", int32(1)}},
+			D{{"_id", int32(1)}, {"name", "john"}, {"tags", A{"e"}}},
+		},
+		{
+			D{{"items", D{{"$elemMatch", D{{"qty", D{{"$gt", int32(5)}}}}}}}},
+			D{{"_id", int32(1)}, {"items", A{D{{"sku", "y"}, {"qty", int32(10)}}}}},
+		},
+		{
+			D{{"items", D{{"$elemMatch", D{{"qty", int32(100)}}}}}, {"name", int32(1)}},
+			D{{"_id", int32(1)}, {"name", "john"}},
+		},
+		{
+			D{{"tags", D{{"$elemMatch", D{{"$gte", "c"}}}}}, {"_id", int32(0)}},
+			D{{"tags", A{"c"}}},
+		},
+	}
+
+	for _, tc := range testCases {
+		have, err := Project(doc, tc.projection)
+		mustOk(t, err)
+		mustDeepEqual(t, have, tc.want)
+
+		raw := RawObject(must(Marshal(doc)))
+		have, err = Project(raw, tc.projection)
+		mustOk(t, err)
+		mustDeepEqual(t, have, tc.want)
+	}
+}
+
+func TestProjectErrors(t *testing.T) {
+	projections := []D{
+		{{"a", int32(1)}, {"b", int32(0)}},
+		{{"a", int32(0)}, {"b", D{{"$elemMatch", D{{"x", int32(1)}}}}}},
+		{{"a", int32(1)}, {"a.b", int32(1)}},
+		{{"a.b", int32(1)}, {"a", int32(1)}},
+		{{"a..b", int32(1)}},
+		{{"$a", int32(1)}},
+		{{"a", "x"}},
+		{{"a", D{{"$foo", int32(1)}}}},
+		{{"a", D{{"$slice", "x"}}}},
+		{{"a", D{{"$slice", A{int32(1), int32(0)}}}}},
+		{{"a.b", D{{"$elemMatch", D{{"x", int32(1)}}}}}},
+		{{"a", D{{"$elemMatch", int32(1)}}}},
+	}
+
+	for _, p := range projections {
+		if _, err := Project(D{}, p); err == nil {
+			t.Fatalf("projection %v must fail", p)
+		}
+	}
+
+	_, err := Project("x", D{})
+	mustFail(t, err)
+
+	_, err = Project(RawObject{1, 2, 3}, D{})
+	mustFail(t, err)
+}
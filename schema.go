@@ -0,0 +1,328 @@
+package bson
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Schema is a compiled MongoDB $jsonSchema validator.
+//
+// Supported keywords:
+//
+//	bsonType, required, properties, additionalProperties, enum,
+//	minimum, maximum, exclusiveMinimum, exclusiveMaximum, pattern, items,
+//	title and description (ignored).
+type Schema struct {
+	root *schemaNode
+}
+
+// NewSchema compiles a $jsonSchema document.
+// The schema can be passed as is or wrapped into {"$jsonSchema": ...} as in collection validators.
+//
+// Example:
+//
+//	s, err := bson.NewSchema(bson.D{
+//		{"bsonType", "object"},
+//		{"required", bson.A{"name"}},
+//		{"properties", bson.D{{"name", bson.D{{"bsonType", "string"}}}}},
+//	})
+func NewSchema(schema any) (*Schema, error) {
+	if raw, ok := schema.(RawObject); ok {
+		d, err := rawToD(raw, 1)
+		if err != nil {
+			return nil, err
+		}
+		schema = d
+	}
+
+	doc, ok := asDocument(schema)
+	if !ok {
+		return nil, fmt.Errorf("schema must be a document, got %T", schema)
+	}
+	if v, ok := doc.Get("$jsonSchema"); ok && len(doc) == 1 {
+		if doc, ok = asDocument(v); !ok {
+			return nil, fmt.Errorf("$jsonSchema must be a document, got %T", v)
+		}
+	}
+
+	root, err := compileSchema(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{root: root}, nil
+}
+
+// Validate checks the document against the schema.
+// The document can be D, M, map[string]any, a struct or RawObject.
+// All violations are reported in a [*SchemaError].
+func (s *Schema) Validate(doc any) error {
+	if raw, ok := doc.(RawObject); ok {
+		d, err := rawToD(raw, 1)
+		if err != nil {
+			return err
+		}
+		doc = d
+	}
+
+	if err := checkCanonical(doc, 1); err != nil {
+		return err
+	}
+
+	var errs []SchemaViolation
+	s.root.validate("", doc, &errs)
+	if len(errs) > 0 {
+		return &SchemaError{Violations: errs}
+	}
+	return nil
+}
+
+// SchemaViolation is a single failed check.
+type SchemaViolation struct {
+	// Path is a dot-notation path of the element, empty for the document itself.
+	Path string
+	// Keyword of the schema which failed, like bsonType or required.
+	Keyword string
+	Message string
+}
+
+// SchemaError is returned by [Schema.Validate] when the document doesn't match the schema.
+type SchemaError struct {
+	Violations []SchemaViolation
+}
+
+// Error implements [error].
+func (e *SchemaError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("bson: document failed validation: ")
+	for i, v := range e.Violations {
+		if i > 0 {
+			sb.WriteString("; ")
+		}
+		if v.Path != "" {
+			sb.WriteString(v.Path)
+			sb.WriteString(": ")
+		}
+		sb.WriteString(v.Message)
+	}
+	return sb.String()
+}
+
+type schemaNode struct {
+	bsonType   func(v any) bool
+	typeNames  any
+	required   []string
+	properties D // values are *schemaNode
+	additional *schemaNode
+	noExtra    bool
+	enum       A
+	minimum    any
+	maximum    any
+	exclMin    bool
+	exclMax    bool
+	pattern    *regexp.Regexp
+	items      *schemaNode
+	itemsList  []*schemaNode
+}
+
+func compileSchema(doc D) (*schemaNode, error) {
+	n := &schemaNode{}
+
+	for _, elem := range doc {
+		var err error
+
+		switch elem.Key {
+		case "bsonType":
+			n.typeNames = elem.Value
+			n.bsonType, err = compileType(elem.Value)
+
+		case "required":
+			arr, ok := asArray(elem.Value)
+			if !ok || len(arr) == 0 {
+				return nil, fmt.Errorf("required must be a nonempty array")
+			}
+			for _, v := range arr {
+				key, ok := v.(string)
+				if !ok {
+					return nil, fmt.Errorf("required must contain strings, got %T", v)
+				}
+				n.required = append(n.required, key)
+			}
+
+		case "properties":
+			props, ok := asDocument(elem.Value)
+			if !ok {
+				return nil, fmt.Errorf("properties must be a document")
+			}
+			for _, prop := range props {
+				p, err := compileSubschema(prop.Value)
+				if err != nil {
+					return nil, fmt.Errorf("properties.%s: %w", prop.Key, err)
+				}
+				n.properties = append(n.properties, E{Key: prop.Key, Value: p})
+			}
+
+		case "additionalProperties":
+			if b, ok := elem.Value.(bool); ok {
+				n.noExtra = !b
+				break
+			}
+			n.additional, err = compileSubschema(elem.Value)
+
+		case "enum":
+			arr, ok := asArray(elem.Value)
+			if !ok || len(arr) == 0 {
+				return nil, fmt.Errorf("enum must be a nonempty array")
+			}
+			n.enum = arr
+
+		case "minimum", "maximum":
+			if _, ok := asNumber(elem.Value); !ok {
+				return nil, fmt.Errorf("%s must be a number", elem.Key)
+			}
+			if elem.Key == "minimum" {
+				n.minimum = elem.Value
+			} else {
+				n.maximum = elem.Value
+			}
+
+		case "exclusiveMinimum", "exclusiveMaximum":
+			b, ok := elem.Value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a bool", elem.Key)
+			}
+			if elem.Key == "exclusiveMinimum" {
+				n.exclMin = b
+			} else {
+				n.exclMax = b
+			}
+
+		case "pattern":
+			s, ok := elem.Value.(string)
+			if !ok {
+				return nil, fmt.Errorf("pattern must be a string")
+			}
+			n.pattern, err = regexp.Compile(s)
+
+		case "items":
+			if arr, ok := asArray(elem.Value); ok {
+				for i, v := range arr {
+					item, err := compileSubschema(v)
+					if err != nil {
+						return nil, fmt.Errorf("items.%d: %w", i, err)
+					}
+					n.itemsList = append(n.itemsList, item)
+				}
+				break
+			}
+			n.items, err = compileSubschema(elem.Value)
+
+		case "title", "description":
+
+		default:
+			return nil, fmt.Errorf("unsupported schema keyword %q", elem.Key)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", elem.Key, err)
+		}
+	}
+	return n, nil
+}
+
+func compileSubschema(v any) (*schemaNode, error) {
+	doc, ok := asDocument(v)
+	if !ok {
+		return nil, fmt.Errorf("schema must be a document, got %T", v)
+	}
+	return compileSchema(doc)
+}
+
+func (n *schemaNode) validate(path string, v any, errs *[]SchemaViolation) {
+	report := func(keyword, format string, args ...any) {
+		*errs = append(*errs, SchemaViolation{
+			Path:    path,
+			Keyword: keyword,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if n.bsonType != nil && !n.bsonType(v) {
+		typ, _ := typeOf(v)
+		report("bsonType", "type must be %v, got %s", n.typeNames, typ)
+		return
+	}
+
+	if n.enum != nil && !containsValue(n.enum, v) {
+		report("enum", "value %v is not one of %v", v, n.enum)
+	}
+
+	if _, ok := asNumber(v); ok {
+		if n.minimum != nil {
+			c := Compare(v, n.minimum)
+			if c < 0 || (c == 0 && n.exclMin) {
+				report("minimum", "value %v is less than minimum %v", v, n.minimum)
+			}
+		}
+		if n.maximum != nil {
+			c := Compare(v, n.maximum)
+			if c > 0 || (c == 0 && n.exclMax) {
+				report("maximum", "value %v is greater than maximum %v", v, n.maximum)
+			}
+		}
+	}
+
+	if s, ok := v.(string); ok && n.pattern != nil && !n.pattern.MatchString(s) {
+		report("pattern", "value %q does not match pattern %q", s, n.pattern)
+	}
+
+	if doc, ok := asDocument(v); ok {
+		n.validateDoc(path, doc, errs)
+	}
+
+	if arr, ok := asArray(v); ok {
+		for i, elem := range arr {
+			item := n.items
+			if n.itemsList != nil {
+				item = nil
+				if i < len(n.itemsList) {
+					item = n.itemsList[i]
+				}
+			}
+			if item != nil {
+				item.validate(joinPath(path, strconv.Itoa(i)), elem, errs)
+			}
+		}
+	}
+}
+
+func (n *schemaNode) validateDoc(path string, doc D, errs *[]SchemaViolation) {
+	for _, key := range n.required {
+		if !doc.Has(key) {
+			*errs = append(*errs, SchemaViolation{
+				Path:    joinPath(path, key),
+				Keyword: "required",
+				Message: "required field is missing",
+			})
+		}
+	}
+
+	for _, elem := range doc {
+		if p, ok := n.properties.Get(elem.Key); ok {
+			p.(*schemaNode).validate(joinPath(path, elem.Key), elem.Value, errs)
+			continue
+		}
+
+		switch {
+		case n.noExtra:
+			*errs = append(*errs, SchemaViolation{
+				Path:    joinPath(path, elem.Key),
+				Keyword: "additionalProperties",
+				Message: "additional field is not allowed",
+			})
+		case n.additional != nil:
+			n.additional.validate(joinPath(path, elem.Key), elem.Value, errs)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package bson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	s, err := NewSchema(D{{"$jsonSchema", D{
+		{"bsonType", "object"},
+		{"required", A{"name", "age"}},
+		{"additionalProperties", false},
+		{"properties", D{
+			{"_id", D{{"bsonType", "objectId"}}},
+			{"name", D{{"bsonType", "string"}, {"pattern", "^[A-Z]"}}},
+			{"age", D{{"bsonType", A{"int", "long"}}, {"minimum", int32(0)}, {"maximum", int32(150)}}},
+			{"score", D{{"bsonType", "number"}, {"minimum", int32(0)}, {"exclusiveMinimum", true}}},
+			{"status", D{{"enum", A{"active", "banned"}}}},
+			{"tags", D{{"bsonType", "array"}, {"items", D{{"bsonType", "string"}}}}},
+			{"point", D{{"items", A{D{{"bsonType", "double"}}, D{{"bsonType", "double"}}}}}},
+			{"address", D{
+				{"bsonType", "object"},
+				{"required", A{"city"}},
+				{"properties", D{{"city", D{{"bsonType", "string"}}}}},
+				{"additionalProperties", D{{"bsonType", "string"}}},
+			}},
+		}},
+	}}})
+	mustOk(t, err)
+
+	valid := D{
+		{"_id", NewObjectID()},
+		{"name", "John"},
+		{"age", int32(42)},
+		{"score", 0.5},
+		{"status", "active"},
+		{"tags", A{"a", "b"}},
+		{"point", A{1.0, 2.0}},
+		{"address", D{{"city", "Berlin"}, {"zip", "10115"}}},
+	}
+	mustOk(t, s.Validate(valid))
+	mustOk(t, s.Validate(RawObject(must(Marshal(valid)))))
+
+	type address struct {
+		City string `bson:"city"`
+	}
+	type user struct {
+		Name    string  `bson:"name"`
+		Age     int64   `bson:"age"`
+		Address address `bson:"address"`
+	}
+	mustOk(t, s.Validate(user{Name: "John", Age: 42, Address: address{City: "Berlin"}}))
+
+	invalid := D{
+		{"name", "john"},
+		{"score", int32(0)},
+		{"status", "unknown"},
+		{"tags", A{"a", int32(1)}},
+		{"point", A{1.0, "x", "extra"}},
+		{"address", D{{"zip", int32(10115)}}},
+		{"extra", true},
+	}
+	err = s.Validate(invalid)
+
+	var serr *SchemaError
+	if !errors.As(err, &serr) {
+		t.Fatalf("want SchemaError, got %v", err)
+	}
+	mustDeepEqual(t, serr.Violations, []SchemaViolation{
+		{Path: "age", Keyword: "required", Message: "required field is missing"},
+		{Path: "name", Keyword: "pattern", Message: `value "john" does not match pattern "^[A-Z]"`},
+		{Path: "score", Keyword: "minimum", Message: "value 0 is less than minimum 0"},
+		{Path: "status", Keyword: "enum", Message: "value unknown is not one of [active banned]"},
+		{Path: "tags.1", Keyword: "bsonType", Message: "type must be string, got int"},
+		{Path: "point.1", Keyword: "bsonType", Message: "type must be double, got string"},
+		{Path: "address.city", Keyword: "required", Message: "required field is missing"},
+		{Path: "address.zip", Keyword: "bsonType", Message: "type must be string, got int"},
+		{Path: "extra", Keyword: "additionalProperties", Message: "additional field is not allowed"},
+	})
+
+	err = s.Validate(D{{"name", "John"}, {"age", "42"}})
+	mustEqual(t, err.Error(), "bson: document failed validation: age: type must be [int long], got string")
+
+	err = s.Validate(RawObject{1, 2, 3})
+	mustFail(t, err)
+
+	err = s.Validate(D{{"name", "John"}, {"x", RawObject{1, 2, 3}}})
+	mustFail(t, err)
+	var se *SchemaError
+	mustEqual(t, errors.As(err, &se), false)
+}
+
+func TestSchemaErrors(t *testing.T) {
+	schemas := []any{
+		"x",
+		D{{"$jsonSchema", "x"}},
+		D{{"bsonType", "nope"}},
+		D{{"required", A{}}},
+		D{{"required", A{int32(1)}}},
+		D{{"properties", "x"}},
+		D{{"properties", D{{"a", "x"}}}},
+		D{{"properties", D{{"a", D{{"bsonType", int32(-1)}, {"foo", int32(1)}}}}}},
+		D{{"additionalProperties", "x"}},
+		D{{"enum", "x"}},
+		D{{"minimum", "x"}},
+		D{{"exclusiveMaximum", int32(1)}},
+		D{{"pattern", "("}},
+		D{{"items", "x"}},
+		D{{"items", A{"x"}}},
+		D{{"minLength", int32(1)}},
+	}
+
+	for _, s := range schemas {
+		if _, err := NewSchema(s); err == nil {
+			t.Fatalf("schema %v must fail", s)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package bson
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// SQLBinary returns a value stored in a database/sql column as binary:
+// ObjectID as 12 bytes and documents as BSON. Suitable for bytea/BLOB columns.
+// This is the default format of [driver.Valuer] implementations in this package.
+//
+// The returned value also implements [sql.Scanner] if v does, Scan accepts both formats.
+func SQLBinary(v any) SQLValue {
+	return SQLValue{v: v}
+}
+
+// SQLJSON returns a value stored in a database/sql column as text:
+// ObjectID as a hex string and documents as relaxed Extended JSON.
+// Suitable for text/json/jsonb columns.
+//
+// The returned value also implements [sql.Scanner] if v does, Scan accepts both formats.
+func SQLJSON(v any) SQLValue {
+	return SQLValue{v: v, text: true}
+}
+
+// SQLValue is a value with the storage format for a database/sql column.
+// See [SQLBinary] and [SQLJSON].
+type SQLValue struct {
+	v    any
+	text bool
+}
+
+// Value implements [driver.Valuer].
+func (s SQLValue) Value() (driver.Value, error) {
+	v := s.v
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		v = rv.Elem().Interface()
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case ObjectID:
+		if s.text {
+			return v.Hex(), nil
+		}
+		return v[:], nil
+	case Timestamp:
+		return v.Value()
+	case RawObject:
+		if v == nil {
+			return nil, nil
+		}
+		if !s.text {
+			return []byte(v), nil
+		}
+	case D:
+		if v == nil {
+			return nil, nil
+		}
+	case M:
+		if v == nil {
+			return nil, nil
+		}
+	}
+	return sqlValue(v, s.text)
+}
+
+// Scan implements [sql.Scanner].
+func (s SQLValue) Scan(src any) error {
+	sc, ok := s.v.(sql.Scanner)
+	if !ok {
+		return fmt.Errorf("cannot scan into %T", s.v)
+	}
+	return sc.Scan(src)
+}
+
+// Value implements [driver.Valuer].
+func (oid ObjectID) Value() (driver.Value, error) {
+	return oid[:], nil
+}
+
+// Scan implements [sql.Scanner].
+func (oid *ObjectID) Scan(src any) error {
+	switch src := src.(type) {
+	case []byte:
+		return oid.UnmarshalBSON(src)
+	case string:
+		id, err := ObjectIDFromHex(src)
+		if err != nil {
+			return err
+		}
+		*oid = id
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into ObjectID", src)
+	}
+}
+
+// Value implements [driver.Valuer].
+func (ts Timestamp) Value() (driver.Value, error) {
+	// driver.Value doesn't allow uint64, keep the bits.
+	return int64(ts), nil
+}
+
+// Scan implements [sql.Scanner].
+func (ts *Timestamp) Scan(src any) error {
+	switch src := src.(type) {
+	case int64:
+		*ts = Timestamp(uint64(src))
+		return nil
+	case []byte:
+		return ts.Scan(string(src))
+	case string:
+		v, err := strconv.ParseInt(src, 10, 64)
+		if err != nil {
+			return fmt.Errorf("cannot scan %q into Timestamp: %w", src, err)
+		}
+		*ts = Timestamp(uint64(v))
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Timestamp", src)
+	}
+}
+
+// Value implements [driver.Valuer].
+func (d D) Value() (driver.Value, error) {
+	if d == nil {
+		return nil, nil
+	}
+	return sqlValue(d, false)
+}
+
+// Scan implements [sql.Scanner].
+func (d *D) Scan(src any) error {
+	doc, err := sqlScan(src)
+	if err != nil {
+		return err
+	}
+	*d = doc
+	return nil
+}
+
+// Value implements [driver.Valuer].
+func (m M) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return sqlValue(m, false)
+}
+
+// Scan implements [sql.Scanner].
+func (m *M) Scan(src any) error {
+	doc, err := sqlScan(src)
+	if err != nil {
+		return err
+	}
+	*m = doc.AsM()
+	return nil
+}
+
+// Value implements [driver.Valuer].
+func (r RawObject) Value() (driver.Value, error) {
+	if r == nil {
+		return nil, nil
+	}
+	return []byte(r), nil
+}
+
+// Scan implements [sql.Scanner].
+func (r *RawObject) Scan(src any) error {
+	if src == nil {
+		*r = nil
+		return nil
+	}
+	if b, ok := src.([]byte); ok && isBSONDocument(b) {
+		*r = append((*r)[:0], b...)
+		return nil
+	}
+
+	doc, err := sqlScan(src)
+	if err != nil {
+		return err
+	}
+	b, err := Marshal(doc)
+	if err != nil {
+		return err
+	}
+	*r = b
+	return nil
+}
+
+func sqlValue(v any, text bool) (driver.Value, error) {
+	if text {
+		b, err := appendExtJSON(nil, v)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	}
+	return Marshal(v)
+}
+
+func sqlScan(src any) (D, error) {
+	switch src := src.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return parseExtJSON([]byte(src))
+	case []byte:
+		if !isBSONDocument(src) {
+			return parseExtJSON(src)
+		}
+		var d D
+		if err := Unmarshal(src, &d); err != nil {
+			return nil, err
+		}
+		return d, nil
+	default:
+		return nil, fmt.Errorf("cannot scan %T into document", src)
+	}
+}
+
+// isBSONDocument reports whether b looks like a BSON document:
+// length prefix matches the slice length and the last byte is 0.
+func isBSONDocument(b []byte) bool {
+	if len(b) < 5 {
+		return false
+	}
+	size, _ := readInt32(b)
+	return size == len(b) && b[len(b)-1] == 0
+}
+
+var (
+	_ driver.Valuer = ObjectID{}
+	_ sql.Scanner   = &ObjectID{}
+	_ driver.Valuer = Timestamp(0)
+	_ sql.Scanner   = new(Timestamp)
+	_ driver.Valuer = D{}
+	_ sql.Scanner   = &D{}
+	_ driver.Valuer = M{}
+	_ sql.Scanner   = &M{}
+	_ driver.Valuer = RawObject{}
+	_ sql.Scanner   = &RawObject{}
+	_ driver.Valuer = SQLValue{}
+	_ sql.Scanner   = SQLValue{}
+)
@@ -0,0 +1,265 @@
+package bson
+
+import (
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cristalhq/bson/bsonproto"
+)
+
+func TestSQLObjectID(t *testing.T) {
+	oid := ObjectID([12]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12})
+
+	v, err := oid.Value()
+	mustOk(t, err)
+	wantBytes(t, v.([]byte), "0102030405060708090a0b0c")
+
+	v, err = SQLJSON(oid).Value()
+	mustOk(t, err)
+	mustEqual(t, v.(string), "0102030405060708090a0b0c")
+
+	v, err = SQLBinary(&oid).Value()
+	mustOk(t, err)
+	wantBytes(t, v.([]byte), "0102030405060708090a0b0c")
+
+	for _, src := range []any{
+		[]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12},
+		[]byte("0102030405060708090a0b0c"),
+		"0102030405060708090a0b0c",
+	} {
+		var id ObjectID
+		mustOk(t, id.Scan(src))
+		mustEqual(t, id, oid)
+	}
+
+	var id ObjectID
+	mustFail(t, id.Scan(int64(1)))
+	mustFail(t, id.Scan("bad"))
+
+	for _, src := range []any{
+		"zz02030405060708090a0b0c",
+		[]byte("0102030405060708090a0bzz"),
+		[]byte{1, 2, 3},
+	} {
+		err := id.Scan(src)
+		mustEqual(t, errors.Is(err, ErrBadObjectID), true)
+	}
+	mustEqual(t, errors.Is(id.UnmarshalBSON([]byte("0102030405060708090a0b0g")), ErrBadObjectID), true)
+}
+
+func TestSQLTimestamp(t *testing.T) {
+	ts := NewTimestampWithCounter(time.Unix(1691690746, 0), 7)
+
+	v, err := ts.Value()
+	mustOk(t, err)
+
+	var got Timestamp
+	mustOk(t, got.Scan(v))
+	mustEqual(t, got, ts)
+
+	mustOk(t, got.Scan([]byte("4294967298")))
+	mustEqual(t, got, Timestamp(4294967298))
+
+	mustFail(t, got.Scan("abc"))
+	mustFail(t, got.Scan(1.5))
+}
+
+func TestSQLDocument(t *testing.T) {
+	oid := ObjectID([12]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12})
+	doc := D{
+		{"a", int32(1)},
+		{"b", "str"},
+		{"c", true},
+		{"d", int64(1 << 40)},
+		{"e", float64(2)},
+	}
+	raw := must(Marshal(doc))
+
+	t.Run("Binary", func(t *testing.T) {
+		v, err := doc.Value()
+		mustOk(t, err)
+		mustEqual(t, string(v.([]byte)), string(raw))
+
+		var d D
+		mustOk(t, d.Scan(v))
+		mustDeepEqual(t, d, doc)
+
+		var m M
+		mustOk(t, m.Scan(v))
+		mustDeepEqual(t, m, doc.AsM())
+
+		var r RawObject
+		mustOk(t, r.Scan(v))
+		mustEqual(t, string(r), string(raw))
+
+		v, err = r.Value()
+		mustOk(t, err)
+		mustEqual(t, string(v.([]byte)), string(raw))
+	})
+
+	t.Run("Text", func(t *testing.T) {
+		v, err := SQLJSON(doc).Value()
+		mustOk(t, err)
+		mustEqual(t, v.(string), `{"a":1,"b":"str","c":true,"d":1099511627776,"e":2.0}`)
+
+		var d D
+		mustOk(t, d.Scan(v))
+		mustDeepEqual(t, d, doc)
+
+		mustOk(t, d.Scan([]byte(v.(string))))
+		mustDeepEqual(t, d, doc)
+
+		var r RawObject
+		mustOk(t, r.Scan(v))
+		mustEqual(t, string(r), string(raw))
+
+		v, err = SQLJSON(RawObject(raw)).Value()
+		mustOk(t, err)
+		mustEqual(t, v.(string), `{"a":1,"b":"str","c":true,"d":1099511627776,"e":2.0}`)
+
+		v, err = SQLJSON(M{"b": "x", "a": oid}).Value()
+		mustOk(t, err)
+		mustEqual(t, v.(string), `{"a":{"$oid":"0102030405060708090a0b0c"},"b":"x"}`)
+
+		var m M
+		mustOk(t, SQLJSON(&m).Scan(v))
+		mustDeepEqual(t, m, M{"a": oid, "b": "x"})
+
+		mustFail(t, SQLJSON(m).Scan(v))
+	})
+
+	t.Run("Null", func(t *testing.T) {
+		d := D{{"a", int32(1)}}
+		mustOk(t, d.Scan(nil))
+		mustEqual(t, d == nil, true)
+
+		r := RawObject(raw)
+		mustOk(t, r.Scan(nil))
+		mustEqual(t, r == nil, true)
+
+		for _, v := range []any{
+			D(nil), M(nil), RawObject(nil),
+			SQLJSON(D(nil)), SQLJSON(M(nil)), SQLJSON(RawObject(nil)),
+			SQLBinary(D(nil)), SQLJSON(nil), SQLJSON((*D)(nil)),
+		} {
+			val, err := v.(driver.Valuer).Value()
+			mustOk(t, err)
+			mustEqual(t, val == nil, true)
+		}
+	})
+
+	t.Run("Bad", func(t *testing.T) {
+		var d D
+		mustFail(t, d.Scan(int64(1)))
+		mustFail(t, d.Scan("[1, 2]"))
+		mustFail(t, d.Scan(`{"a": 1} {}`))
+	})
+}
+
+func TestExtJSON(t *testing.T) {
+	ts := NewTimestampWithCounter(time.Unix(1691690746, 0), 7)
+	tm := time.Date(2023, 8, 10, 18, 5, 46, 123e6, time.UTC)
+
+	doc := D{
+		{"oid", ObjectID([12]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12})},
+		{"ts", ts},
+		{"date", tm},
+		{"re", Regex{Pattern: "^a.*", Options: "i"}},
+		{"arr", A{int32(1), "two", nil}},
+		{"nested", D{{"x", float64(1.5)}}},
+		{"bin", bsonproto.Binary{Subtype: bsonproto.BinaryUUID, B: []byte{1, 2, 3}}},
+		{"empty", bsonproto.Binary{Subtype: bsonproto.BinaryUser}},
+		{"dec", must(parseDecimal("-1.50"))},
+	}
+
+	b, err := appendExtJSON(nil, doc)
+	mustOk(t, err)
+	mustEqual(t, string(b), `{"oid":{"$oid":"0102030405060708090a0b0c"},`+
+		`"ts":{"$timestamp":{"t":1691690746,"i":7}},`+
+		`"date":{"$date":"2023-08-10T18:05:46.123Z"},`+
+		`"re":{"$regularExpression":{"pattern":"^a.*","options":"i"}},`+
+		`"arr":[1,"two",null],`+
+		`"nested":{"x":1.5},`+
+		`"bin":{"$binary":{"base64":"AQID","subType":"04"}},`+
+		`"empty":{"$binary":{"base64":"","subType":"80"}},`+
+		`"dec":{"$numberDecimal":"-1.50"}}`)
+
+	got, err := parseExtJSON(b)
+	mustOk(t, err)
+	mustDeepEqual(t, got, doc)
+
+	got, err = parseExtJSON([]byte(`{"a":{"$numberLong":"5"},"b":{"$date":{"$numberLong":"1691690746123"}},"c":{"$numberDouble":"-Infinity"}}`))
+	mustOk(t, err)
+	mustEqual(t, got[0].Value.(int64), int64(5))
+	mustEqual(t, got[1].Value.(time.Time).Equal(tm), true)
+	mustEqual(t, got[2].Value.(float64) < 0, true)
+
+	b, err = appendExtJSON(nil, D{{"b", []byte{0xff}}})
+	mustOk(t, err)
+	mustEqual(t, string(b), `{"b":{"$binary":{"base64":"/w==","subType":"00"}}}`)
+
+	for _, bad := range []string{
+		`{"b":{"$binary":{"base64":"!","subType":"00"}}}`,
+		`{"b":{"$binary":{"base64":"AA==","subType":"0"}}}`,
+		`{"b":{"$binary":"AA=="}}`,
+		`{"d":{"$numberDecimal":"1.2.3"}}`,
+		`{"d":{"$numberDecimal":1}}`,
+	} {
+		_, err := parseExtJSON([]byte(bad))
+		mustFail(t, err)
+	}
+}
+
+func TestExtJSONDecimal(t *testing.T) {
+	testCases := []struct {
+		in   string
+		want string
+	}{
+		{"0", "0"},
+		{"-0", "-0"},
+		{"1", "1"},
+		{"-1.50", "-1.50"},
+		{"0.001234", "0.001234"},
+		{"0.00000012345", "1.2345E-7"},
+		{"-1.00E-8", "-1.00E-8"},
+		{"1E+3", "1E+3"},
+		{"0E+3", "0E+3"},
+		{"12345678901234567890", "12345678901234567890"},
+		{"1E-6176", "1E-6176"},
+		{"0E-7000", "0E-6176"},
+		{"9.999999999999999999999999999999999E+6144", "9.999999999999999999999999999999999E+6144"},
+		{"1E+6144", "1.000000000000000000000000000000000E+6144"},
+		{"NaN", "NaN"},
+		{"Infinity", "Infinity"},
+		{"-Infinity", "-Infinity"},
+	}
+
+	for _, tc := range testCases {
+		d, err := parseDecimal(tc.in)
+		mustOk(t, err)
+		mustEqual(t, formatDecimal(d), tc.want)
+	}
+
+	for _, bad := range []string{"", ".", "1e", "abc", "1.2.3", "1E+6145", "1E-6177", "1E-9999999"} {
+		_, err := parseDecimal(bad)
+		mustFail(t, err)
+	}
+
+	doc := D{{"b", []byte{1, 2}}, {"d", must(parseDecimal("0.1"))}}
+	v, err := SQLJSON(doc).Value()
+	mustOk(t, err)
+	var got D
+	mustOk(t, got.Scan(v))
+	mustDeepEqual(t, got, D{{"b", bsonproto.Binary{B: []byte{1, 2}}}, {"d", doc[1].Value}})
+}
+
+func mustDeepEqual(tb testing.TB, have, want any) {
+	tb.Helper()
+
+	if !reflect.DeepEqual(have, want) {
+		tb.Fatalf("\nhave: %+v\nwant: %+v\n", have, want)
+	}
+}
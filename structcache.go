@@ -2,6 +2,7 @@ package bson
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
 	"sort"
 	"strings"
@@ -10,8 +11,11 @@ import (
 
 var structInfoCache sync.Map // map[reflect.Type]*structInfo
 
+var typeRawValues = reflect.TypeOf(map[string]RawValue{})
+
 type structInfo struct {
-	Fields []fieldInfo
+	Fields  []fieldInfo
+	Unknown int // number of the field for unknown elements, -1 if none.
 }
 
 type fieldInfo struct {
@@ -34,25 +38,76 @@ func (si *structInfo) asDoc(val reflect.Value) docRefl {
 			Val: value.Interface(),
 		})
 	}
+
+	if si.Unknown != -1 {
+		iter := val.Field(si.Unknown).MapRange()
+		for iter.Next() {
+			if _, ok := si.field(iter.Key().String()); ok {
+				continue
+			}
+			doc = append(doc, pairRefl{
+				Key: iter.Key().String(),
+				Val: iter.Value().Interface(),
+			})
+		}
+	}
 	sort.Sort(doc)
 	return doc
 }
 
-func getStruct(val reflect.Value) *structInfo {
+func (si *structInfo) field(key string) (fieldInfo, bool) {
+	for _, info := range si.Fields {
+		if info.Key == key {
+			return info, true
+		}
+	}
+	return fieldInfo{}, false
+}
+
+// fieldByName returns the field with the given Go name, fields that are
+// unexported, skipped with "-" or collect unknown elements are not matched.
+func (si *structInfo) fieldByName(val reflect.Value, name string) reflect.Value {
+	field, ok := val.Type().FieldByName(name)
+	if !ok || field.PkgPath != "" || bsonTag(field) == "-" {
+		return reflect.Value{}
+	}
+	if len(field.Index) == 1 && field.Index[0] == si.Unknown {
+		return reflect.Value{}
+	}
+	v, err := val.FieldByIndexErr(field.Index)
+	if err != nil {
+		return reflect.Value{}
+	}
+	return v
+}
+
+func bsonTag(field reflect.StructField) string {
+	tag := field.Tag.Get("bson")
+	if tag == "" && strings.Index(string(field.Tag), ":") == -1 {
+		tag = string(field.Tag)
+	}
+	return tag
+}
+
+func getStruct(val reflect.Value) (*structInfo, error) {
 	typ := val.Type()
 	if info, ok := structInfoCache.Load(typ); ok {
-		return info.(*structInfo)
+		return info.(*structInfo), nil
 	}
 
-	info, _ := getStructInfo(typ)
+	info, err := getStructInfo(typ)
+	if err != nil {
+		return nil, err
+	}
 	structInfoCache.Store(typ, info)
-	return info
+	return info, nil
 }
 
 func getStructInfo(typ reflect.Type) (*structInfo, error) {
 	n := typ.NumField()
 	fields := make([]fieldInfo, 0, n)
 	fieldsMap := make(map[string]fieldInfo, n)
+	unknownNum := -1
 
 	for i := 0; i < n; i++ {
 		field := typ.Field(i)
@@ -61,11 +116,9 @@ func getStructInfo(typ reflect.Type) (*structInfo, error) {
 		}
 
 		info := fieldInfo{Num: i}
+		unknown := false
 
-		tag := field.Tag.Get("bson")
-		if tag == "" && strings.Index(string(field.Tag), ":") == -1 {
-			tag = string(field.Tag)
-		}
+		tag := bsonTag(field)
 		if tag == "-" {
 			continue
 		}
@@ -76,6 +129,11 @@ func getStructInfo(typ reflect.Type) (*structInfo, error) {
 				switch flag {
 				case "omitempty":
 					info.OmitEmpty = true
+				case "unknown":
+					if field.Type != typeRawValues {
+						return nil, fmt.Errorf("bson: field %s with unknown flag must be map[string]RawValue, got %v", field.Name, field.Type)
+					}
+					unknown = true
 				default:
 					panic("Unsupported flag: " + flag)
 				}
@@ -83,6 +141,11 @@ func getStructInfo(typ reflect.Type) (*structInfo, error) {
 			tag = tagsParts[0]
 		}
 
+		if unknown {
+			unknownNum = i
+			continue
+		}
+
 		if tag != "" {
 			info.Key = tag
 		} else {
@@ -98,7 +161,8 @@ func getStructInfo(typ reflect.Type) (*structInfo, error) {
 	}
 
 	info := &structInfo{
-		Fields: fields,
+		Fields:  fields,
+		Unknown: unknownNum,
 	}
 	return info, nil
 }
@@ -7,10 +7,15 @@ import (
 	"fmt"
 	"sync/atomic"
 	"time"
+
+	"github.com/cristalhq/bson/bsonproto"
 )
 
 // Timestamp represents BSON type Timestamp.
-type Timestamp int64
+//
+// The higher 32 bits are seconds since the Unix epoch (T)
+// and the lower 32 bits are an incrementing ordinal (I).
+type Timestamp uint64
 
 // NowTimestamp returns a timestamp with current UTC time.
 func NowTimestamp() Timestamp {
@@ -26,23 +31,66 @@ func NewTimestamp(t time.Time) Timestamp {
 
 // NewTimestampWithCounter returns a timestamp with a given time and counter.
 func NewTimestampWithCounter(t time.Time, c uint32) Timestamp {
-	return Timestamp((t.UTC().Unix() << 32) | int64(c))
+	return Timestamp(uint64(uint32(t.UTC().Unix()))<<32 | uint64(c))
+}
+
+// TimestampFromProto returns a timestamp from [bsonproto.Timestamp].
+func TimestampFromProto(ts bsonproto.Timestamp) Timestamp {
+	return Timestamp(ts)
+}
+
+// Proto returns timestamp as [bsonproto.Timestamp].
+func (ts Timestamp) Proto() bsonproto.Timestamp {
+	return bsonproto.Timestamp(ts)
 }
 
-// String returns a hex string representation of the id.
-// Example: Timestamp('4398046513152, 1024').
+// String returns a string representation of the timestamp.
+// Example: Timestamp(1691690746, 1).
 func (ts Timestamp) String() string {
-	return fmt.Sprintf(`Timestamp(%d, %d)`, ts, ts>>32)
+	return fmt.Sprintf(`Timestamp(%d, %d)`, ts.T(), ts.I())
+}
+
+// T returns seconds since the Unix epoch.
+func (ts Timestamp) T() uint32 {
+	return uint32(ts >> 32)
+}
+
+// I returns the increment ordinal.
+func (ts Timestamp) I() uint32 {
+	return uint32(ts)
 }
 
-// Time returns time.Time ignoring increment.
+// Time returns time.Time in UTC ignoring increment.
 func (ts Timestamp) Time() time.Time {
-	return time.Unix(int64(ts>>32), 0)
+	return time.Unix(int64(ts.T()), 0).UTC()
 }
 
-// Counter returns timestamp counter.
+// Counter returns timestamp counter, same as [Timestamp.I].
 func (ts Timestamp) Counter() uint32 {
-	return uint32(ts)
+	return ts.I()
+}
+
+// Compare returns an integer comparing two timestamps by T and then by I.
+// The result will be 0 if ts == other, -1 if ts < other, and +1 if ts > other.
+func (ts Timestamp) Compare(other Timestamp) int {
+	switch {
+	case ts < other:
+		return -1
+	case ts > other:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// After reports whether ts is after other.
+func (ts Timestamp) After(other Timestamp) bool {
+	return ts > other
+}
+
+// Before reports whether ts is before other.
+func (ts Timestamp) Before(other Timestamp) bool {
+	return ts < other
 }
 
 // MarshalBSON implements [Marshaler].
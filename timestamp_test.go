@@ -1,9 +1,55 @@
 package bson
 
-import "testing"
+import (
+	"testing"
+	"time"
+
+	"github.com/cristalhq/bson/bsonproto"
+)
 
 func TestTimestamp(t *testing.T) {
 	ts := Timestamp(4294967298 << 10)
 
-	mustEqual(t, ts.String(), "Timestamp(4398046513152, 1024)")
+	mustEqual(t, ts.String(), "Timestamp(1024, 2048)")
+	mustEqual(t, ts.T(), uint32(1024))
+	mustEqual(t, ts.I(), uint32(2048))
+	mustEqual(t, ts.Counter(), uint32(2048))
+	mustEqual(t, ts.Time(), time.Unix(1024, 0).UTC())
+	mustEqual(t, ts.Time().Location(), time.UTC)
+}
+
+func TestTimestampNew(t *testing.T) {
+	now := time.Unix(1691690746, 0)
+	ts := NewTimestampWithCounter(now, 7)
+
+	mustEqual(t, ts.String(), "Timestamp(1691690746, 7)")
+	mustEqual(t, ts.Time(), now.UTC())
+
+	// seconds after 2038 must not be treated as negative.
+	late := time.Unix(1<<31+10, 0)
+	mustEqual(t, NewTimestampWithCounter(late, 1).Time(), late.UTC())
+}
+
+func TestTimestampCompare(t *testing.T) {
+	a := NewTimestampWithCounter(time.Unix(100, 0), 5)
+	b := NewTimestampWithCounter(time.Unix(100, 0), 6)
+	c := NewTimestampWithCounter(time.Unix(101, 0), 0)
+
+	mustEqual(t, a.Compare(b), -1)
+	mustEqual(t, b.Compare(a), 1)
+	mustEqual(t, a.Compare(a), 0)
+	mustEqual(t, b.Compare(c), -1)
+
+	mustEqual(t, a.Before(b), true)
+	mustEqual(t, a.After(b), false)
+	mustEqual(t, c.After(b), true)
+	mustEqual(t, c.Before(c), false)
+}
+
+func TestTimestampProto(t *testing.T) {
+	ts := NewTimestampWithCounter(time.Unix(1691690746, 0), 7)
+
+	p := ts.Proto()
+	mustEqual(t, p, bsonproto.Timestamp(uint64(1691690746)<<32|7))
+	mustEqual(t, TimestampFromProto(p), ts)
 }
@@ -1,5 +1,13 @@
 package bson
 
+import (
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/cristalhq/bson/bsonproto"
+)
+
 // Type represents a BSON type.
 type Type byte
 
@@ -27,3 +35,103 @@ const (
 	TypeMinKey          Type = 0xff
 	TypeMaxKey          Type = 0x7f
 )
+
+// String returns the type alias as used by MongoDB ($type, $jsonSchema bsonType).
+func (t Type) String() string {
+	for alias, typ := range typeAliases {
+		if typ == t {
+			return alias
+		}
+	}
+	return "Type(" + strconv.Itoa(int(t)) + ")"
+}
+
+// typeAliases maps MongoDB type aliases to types.
+var typeAliases = map[string]Type{
+	"double":              TypeDouble,
+	"string":              TypeString,
+	"object":              TypeDocument,
+	"array":               TypeArray,
+	"binData":             TypeBinary,
+	"undefined":           TypeUndefined,
+	"objectId":            TypeObjectID,
+	"bool":                TypeBool,
+	"date":                TypeDateTime,
+	"null":                TypeNull,
+	"regex":               TypeRegex,
+	"dbPointer":           TypeDBPointer,
+	"javascript":          TypeCodeWithScope,
+	"symbol":              TypeSymbol,
+	"javascriptWithScope": TypeJavaScriptScope,
+	"int":                 TypeInt32,
+	"timestamp":           TypeTimestamp,
+	"long":                TypeInt64,
+	"decimal":             TypeDecimal,
+	"minKey":              TypeMinKey,
+	"maxKey":              TypeMaxKey,
+}
+
+// typeOf returns BSON type of the Go value as it will be encoded.
+// Reports false if the value cannot be encoded.
+func typeOf(v any) (Type, bool) {
+	switch v.(type) {
+	case nil:
+		return TypeNull, true
+	case float32, float64:
+		return TypeDouble, true
+	case string:
+		return TypeString, true
+	case D, M, map[string]any, RawObject:
+		return TypeDocument, true
+	case A, []any, RawArray:
+		return TypeArray, true
+	case []byte, bsonproto.Binary:
+		return TypeBinary, true
+	case ObjectID:
+		return TypeObjectID, true
+	case bool:
+		return TypeBool, true
+	case time.Time:
+		return TypeDateTime, true
+	case Regex:
+		return TypeRegex, true
+	case int, int8, int16, int32, uint, uint8, uint16, uint32:
+		return TypeInt32, true
+	case Timestamp:
+		return TypeTimestamp, true
+	case int64, uint64:
+		return TypeInt64, true
+	case bsonproto.Decimal128:
+		return TypeDecimal, true
+	case MinKey:
+		return TypeMinKey, true
+	case MaxKey:
+		return TypeMaxKey, true
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return TypeNull, true
+		}
+		return typeOf(rv.Elem().Interface())
+	case reflect.Struct, reflect.Map:
+		return TypeDocument, true
+	case reflect.Slice, reflect.Array:
+		return TypeArray, true
+	case reflect.String:
+		return TypeString, true
+	case reflect.Bool:
+		return TypeBool, true
+	case reflect.Float32, reflect.Float64:
+		return TypeDouble, true
+	case reflect.Int64, reflect.Uint64:
+		return TypeInt64, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return TypeInt32, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,66 @@
+package bson
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// ErrUnknownField is wrapped by [UnknownFieldsError].
+var ErrUnknownField = errors.New("bson: unknown field")
+
+// UnknownFieldsError is returned when the decoder is configured with [Decoder.DisallowUnknownFields]
+// and the document has elements without matching struct fields.
+type UnknownFieldsError struct {
+	// Fields are dot-notation paths of the unknown elements, like items.3.color.
+	Fields []string
+}
+
+// Error implements [error].
+func (e *UnknownFieldsError) Error() string {
+	return "bson: unknown fields: " + strings.Join(e.Fields, ", ")
+}
+
+// Unwrap returns [ErrUnknownField].
+func (e *UnknownFieldsError) Unwrap() error {
+	return ErrUnknownField
+}
+
+// DisallowUnknownFields causes the decoder to return an [UnknownFieldsError]
+// when the destination is a struct and the document has elements without matching fields.
+// All the unknown elements are reported, decoding of known ones is not stopped.
+//
+// Elements are not unknown if the struct has a field of type map[string]RawValue
+// with the `bson:",unknown"` tag, such field gets all the unmatched elements.
+func (dec *Decoder) DisallowUnknownFields() {
+	dec.disallowUnknown = true
+}
+
+// unknownField handles the element without matching field in the struct v.
+func (ds *decodeState) unknownField(info *structInfo, v reflect.Value, typ Type, key string, element []byte) {
+	if info.Unknown != -1 {
+		m := v.Field(info.Unknown)
+		if m.IsNil() {
+			m.Set(reflect.MakeMap(m.Type()))
+		}
+		m.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(newRawValue(typ, element)))
+		return
+	}
+	if ds.disallowUnknown {
+		ds.unknown = append(ds.unknown, joinPath(strings.Join(ds.path, "."), key))
+	}
+}
+
+// push adds the element name to the path when unknown fields are tracked.
+func (ds *decodeState) push(name []byte) {
+	if ds.disallowUnknown {
+		ds.path = append(ds.path, trimlast(name))
+	}
+}
+
+// pop removes the last element name from the path.
+func (ds *decodeState) pop() {
+	if ds.disallowUnknown {
+		ds.path = ds.path[:len(ds.path)-1]
+	}
+}
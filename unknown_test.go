@@ -0,0 +1,117 @@
+package bson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDisallowUnknownFields(t *testing.T) {
+	type item struct {
+		Name string `bson:"name"`
+	}
+	type config struct {
+		Name  string          `bson:"name"`
+		Items []item          `bson:"items"`
+		Sub   *item           `bson:"sub"`
+		Map   map[string]item `bson:"map"`
+		Any   any             `bson:"any"`
+	}
+
+	raw := must(Marshal(D{
+		{"name", "x"},
+		{"color", "red"},
+		{"items", A{D{{"name", "a"}}, D{{"name", "b"}, {"size", int32(1)}}}},
+		{"sub", D{{"name", "s"}, {"extra", true}}},
+		{"map", D{{"k", D{{"name", "v"}, {"x", int32(1)}}}}},
+		{"any", D{{"whatever", int32(1)}}},
+	}))
+
+	var c config
+	mustOk(t, Unmarshal(raw, &c))
+
+	c = config{}
+	dec := NewDecodeBytes(raw)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&c)
+	mustEqual(t, errors.Is(err, ErrUnknownField), true)
+
+	var uerr *UnknownFieldsError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("want UnknownFieldsError, got %v", err)
+	}
+	mustDeepEqual(t, uerr.Fields, []string{"color", "items.1.size", "sub.extra", "map.k.x"})
+	mustEqual(t, err.Error(), "bson: unknown fields: color, items.1.size, sub.extra, map.k.x")
+
+	// known fields are still decoded.
+	mustEqual(t, c.Name, "x")
+	mustEqual(t, c.Sub.Name, "s")
+	mustEqual(t, len(c.Items), 2)
+
+	dec = NewDecodeBytes(must(Marshal(D{{"name", "x"}})))
+	dec.DisallowUnknownFields()
+	mustOk(t, dec.Decode(&c))
+
+	type inner struct {
+		X int32 `bson:"x"`
+	}
+	type hidden struct {
+		inner
+		Name    string `bson:"name"`
+		Skip    string `bson:"-"`
+		private string
+	}
+	dec = NewDecodeBytes(must(Marshal(D{
+		{"name", "x"},
+		{"Skip", "a"},
+		{"private", "b"},
+		{"inner", D{{"x", int32(1)}}},
+		{"Name", "y"},
+	})))
+	dec.DisallowUnknownFields()
+	var h hidden
+	err = dec.Decode(&h)
+	if !errors.As(err, &uerr) {
+		t.Fatalf("want UnknownFieldsError, got %v", err)
+	}
+	mustDeepEqual(t, uerr.Fields, []string{"Skip", "private", "inner"})
+	mustEqual(t, h.Name, "y")
+	mustEqual(t, h.Skip, "")
+	mustEqual(t, h.private, "")
+}
+
+func TestUnknownFieldsCollect(t *testing.T) {
+	type record struct {
+		Name  string              `bson:"name"`
+		Extra map[string]RawValue `bson:",unknown"`
+	}
+
+	raw := must(Marshal(D{
+		{"age", int32(42)},
+		{"color", "red"},
+		{"name", "x"},
+		{"tags", A{"a", "b"}},
+	}))
+
+	var r record
+	dec := NewDecodeBytes(raw)
+	dec.DisallowUnknownFields()
+	mustOk(t, dec.Decode(&r))
+
+	mustEqual(t, r.Name, "x")
+	mustEqual(t, len(r.Extra), 3)
+	mustEqual(t, r.Extra["age"].Type, TypeInt32)
+	mustDeepEqual(t, r.Extra["age"].Data, []byte{42, 0, 0, 0})
+	mustEqual(t, r.Extra["tags"].Type, TypeArray)
+
+	// unknown fields are encoded back.
+	mustDeepEqual(t, must(Marshal(r)), raw)
+	mustEqual(t, Equal(r, D{{"age", int32(42)}, {"color", "red"}, {"name", "x"}, {"tags", A{"a", "b"}}}, nil), true)
+
+	type invalid struct {
+		Extra map[string]any `bson:",unknown"`
+	}
+	_, err := Marshal(invalid{})
+	mustFail(t, err)
+	var inv invalid
+	mustFail(t, Unmarshal(raw, &inv))
+}
@@ -0,0 +1,456 @@
+package bson
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cristalhq/bson/bsonproto"
+)
+
+// timeNow is used by $currentDate, replaced in tests.
+var timeNow = time.Now
+
+// ApplyUpdate applies MongoDB update operators to a copy of the document.
+// The input document is not modified.
+//
+// Supported operators:
+//
+//	$set, $unset, $inc, $mul, $min, $max, $rename, $currentDate,
+//	$push (with $each, $slice, $sort, $position), $addToSet (with $each), $pull, $pop.
+//
+// Existing fields keep their positions, new fields are appended to the end of the document.
+// Fields of a single operator are applied in lexicographic order as MongoDB does.
+//
+// Example:
+//
+//	doc, err := bson.ApplyUpdate(doc, bson.D{
+//		{"$set", bson.D{{"address.city", "Berlin"}}},
+//		{"$inc", bson.D{{"visits", 1}}},
+//	})
+func ApplyUpdate(doc, update D) (D, error) {
+	if err := checkUpdate(update); err != nil {
+		return nil, err
+	}
+	if err := checkCanonical(doc, 1); err != nil {
+		return nil, err
+	}
+	if err := checkCanonical(update, 1); err != nil {
+		return nil, err
+	}
+
+	res, err := DeepCopyErr(doc)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		res = D{}
+	}
+
+	for _, op := range update {
+		fields, _ := asDocument(op.Value)
+		fields = append(D{}, fields...)
+		sort.SliceStable(fields, func(i, j int) bool {
+			return fields[i].Key < fields[j].Key
+		})
+
+		for _, field := range fields {
+			value, err := deepCopyValue(field.Value, 1)
+			if err != nil {
+				return nil, err
+			}
+			if err := applyOperator(&res, op.Key, field.Key, value); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", op.Key, field.Key, err)
+			}
+		}
+	}
+	return res, nil
+}
+
+// checkUpdate validates the structure of the update and checks for conflicting paths.
+func checkUpdate(update D) error {
+	var paths []string
+
+	for _, op := range update {
+		if !strings.HasPrefix(op.Key, "$") {
+			return fmt.Errorf("update must contain only operators, got %q", op.Key)
+		}
+		if _, ok := updateOperators[op.Key]; !ok {
+			return fmt.Errorf("unknown update operator %s", op.Key)
+		}
+
+		fields, ok := asDocument(op.Value)
+		if !ok {
+			return fmt.Errorf("%s must be a document", op.Key)
+		}
+
+		for _, field := range fields {
+			if _, err := splitPath(field.Key); err != nil {
+				return fmt.Errorf("%s: %w", op.Key, err)
+			}
+			paths = append(paths, field.Key)
+
+			if op.Key == "$rename" {
+				to, ok := field.Value.(string)
+				if !ok {
+					return fmt.Errorf("$rename %s: target must be a string", field.Key)
+				}
+				if _, err := splitPath(to); err != nil {
+					return fmt.Errorf("$rename %s: %w", field.Key, err)
+				}
+				paths = append(paths, to)
+			}
+		}
+	}
+
+	sort.Strings(paths)
+	for i := 1; i < len(paths); i++ {
+		prev, cur := paths[i-1], paths[i]
+		if prev == cur || strings.HasPrefix(cur, prev+".") {
+			return fmt.Errorf("updating the path %q would create a conflict at %q", cur, prev)
+		}
+	}
+	return nil
+}
+
+var updateOperators = map[string]struct{}{
+	"$set":         {},
+	"$unset":       {},
+	"$inc":         {},
+	"$mul":         {},
+	"$min":         {},
+	"$max":         {},
+	"$rename":      {},
+	"$currentDate": {},
+	"$push":        {},
+	"$addToSet":    {},
+	"$pull":        {},
+	"$pop":         {},
+}
+
+func applyOperator(doc *D, op, path string, value any) error {
+	current, err := GetPath(*doc, path)
+	exists := err == nil
+	if err != nil && !errors.Is(err, ErrElementNotFound) {
+		return err
+	}
+
+	switch op {
+	case "$set":
+		return SetPath(doc, path, value)
+
+	case "$unset":
+		return UnsetPath(doc, path)
+
+	case "$inc", "$mul":
+		if _, ok := asNumber(value); !ok {
+			return fmt.Errorf("cannot apply %s with non-numeric argument %T", op, value)
+		}
+		if !exists {
+			if op == "$mul" {
+				value, _ = arith("$mul", value, int32(0))
+			}
+			return SetPath(doc, path, value)
+		}
+		res, err := arith(op, current, value)
+		if err != nil {
+			return err
+		}
+		return SetPath(doc, path, res)
+
+	case "$min", "$max":
+		c := Compare(value, current)
+		if !exists || (op == "$min" && c < 0) || (op == "$max" && c > 0) {
+			return SetPath(doc, path, value)
+		}
+		return nil
+
+	case "$rename":
+		if !exists {
+			return nil
+		}
+		if err := UnsetPath(doc, path); err != nil {
+			return err
+		}
+		return SetPath(doc, value.(string), current)
+
+	case "$currentDate":
+		return SetPath(doc, path, currentDate(value))
+
+	case "$push":
+		arr, err := existingArray(current, exists)
+		if err != nil {
+			return err
+		}
+		arr, err = push(arr, value)
+		if err != nil {
+			return err
+		}
+		return SetPath(doc, path, arr)
+
+	case "$addToSet":
+		arr, err := existingArray(current, exists)
+		if err != nil {
+			return err
+		}
+		values := A{value}
+		if each, ok := eachModifier(value); ok {
+			values = each
+		}
+		for _, v := range values {
+			if !containsValue(arr, v) {
+				arr = append(arr, v)
+			}
+		}
+		return SetPath(doc, path, arr)
+
+	case "$pull":
+		if !exists {
+			return nil
+		}
+		arr, err := existingArray(current, exists)
+		if err != nil {
+			return err
+		}
+		match, err := pullMatcher(value)
+		if err != nil {
+			return err
+		}
+		res := A{}
+		for _, v := range arr {
+			if !match(v) {
+				res = append(res, v)
+			}
+		}
+		return SetPath(doc, path, res)
+
+	case "$pop":
+		n, ok := asInt(value)
+		if !ok || (n != 1 && n != -1) {
+			return errors.New("$pop expects 1 or -1")
+		}
+		if !exists {
+			return nil
+		}
+		arr, err := existingArray(current, exists)
+		if err != nil || len(arr) == 0 {
+			return err
+		}
+		if n == 1 {
+			arr = arr[:len(arr)-1]
+		} else {
+			arr = arr[1:]
+		}
+		return SetPath(doc, path, arr)
+
+	default:
+		return fmt.Errorf("unknown update operator %s", op)
+	}
+}
+
+// arith returns a+b or a*b keeping the narrowest numeric type that fits the result,
+// the result is Decimal128 if any of the values is Decimal128.
+func arith(op string, a, b any) (any, error) {
+	oa, va := canonicalOrder(a)
+	ob, vb := canonicalOrder(b)
+	if oa != orderNumber || ob != orderNumber {
+		return nil, fmt.Errorf("cannot apply %s to a value of non-numeric type %T", op, a)
+	}
+
+	x, xInt := va.(int64)
+	y, yInt := vb.(int64)
+	if xInt && yInt {
+		var res int64
+		var overflow bool
+		if op == "$inc" {
+			res = x + y
+			overflow = (x > 0 && y > 0 && res < 0) || (x < 0 && y < 0 && res >= 0)
+		} else {
+			res = x * y
+			overflow = x != 0 && (res/x != y || (x == -1 && y == math.MinInt64))
+		}
+		if overflow {
+			return nil, fmt.Errorf("integer overflow in %s", op)
+		}
+
+		_, aIs32 := a.(int32)
+		_, bIs32 := b.(int32)
+		if aIs32 && bIs32 && res >= math.MinInt32 && res <= math.MaxInt32 {
+			return int32(res), nil
+		}
+		return res, nil
+	}
+
+	_, xDec := va.(bsonproto.Decimal128)
+	_, yDec := vb.(bsonproto.Decimal128)
+	if xDec || yDec {
+		res, err := decimalArith(op, va, vb)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		return res, nil
+	}
+
+	fx, ok1 := asNumber(a)
+	fy, ok2 := asNumber(b)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("cannot apply %s to %T and %T", op, a, b)
+	}
+	if op == "$inc" {
+		return fx + fy, nil
+	}
+	return fx * fy, nil
+}
+
+func currentDate(spec any) any {
+	now := timeNow().UTC()
+	if doc, ok := asDocument(spec); ok {
+		if typ, _ := doc.Get("$type"); typ == "timestamp" {
+			return NewTimestampWithCounter(now, 0)
+		}
+	}
+	return now.Truncate(time.Millisecond)
+}
+
+func existingArray(v any, exists bool) (A, error) {
+	if !exists || v == nil {
+		return A{}, nil
+	}
+	arr, ok := asArray(v)
+	if !ok {
+		return nil, fmt.Errorf("cannot apply to a non-array value of type %T", v)
+	}
+	return append(A{}, arr...), nil
+}
+
+func eachModifier(value any) (A, bool) {
+	doc, ok := asDocument(value)
+	if !ok {
+		return nil, false
+	}
+	each, ok := doc.Get("$each")
+	if !ok {
+		return nil, false
+	}
+	arr, ok := asArray(each)
+	return arr, ok
+}
+
+func push(arr A, value any) (A, error) {
+	doc, ok := asDocument(value)
+	if !ok || !doc.Has("$each") {
+		return append(arr, value), nil
+	}
+
+	each, ok := eachModifier(value)
+	if !ok {
+		return nil, errors.New("$each must be an array")
+	}
+
+	position := len(arr)
+	if v, ok := doc.Get("$position"); ok {
+		n, ok := asInt(v)
+		if !ok {
+			return nil, errors.New("$position must be an integer")
+		}
+		if n < 0 {
+			n += len(arr)
+		}
+		if n < 0 {
+			n = 0
+		}
+		if n < position {
+			position = n
+		}
+	}
+
+	res := make(A, 0, len(arr)+len(each))
+	res = append(res, arr[:position]...)
+	res = append(res, each...)
+	res = append(res, arr[position:]...)
+
+	if spec, ok := doc.Get("$sort"); ok {
+		if err := sortArray(res, spec); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := doc.Get("$slice"); ok {
+		n, ok := asInt(v)
+		if !ok {
+			return nil, errors.New("$slice must be an integer")
+		}
+		switch {
+		case n >= 0 && n < len(res):
+			res = res[:n]
+		case n < 0 && -n < len(res):
+			res = res[len(res)+n:]
+		}
+	}
+	return res, nil
+}
+
+// sortArray sorts elements by value (1 or -1) or by fields of documents ({field: 1}).
+func sortArray(arr A, spec any) error {
+	if dir, ok := asInt(spec); ok {
+		if dir != 1 && dir != -1 {
+			return errors.New("$sort must be 1 or -1")
+		}
+		sort.SliceStable(arr, func(i, j int) bool {
+			return Compare(arr[i], arr[j])*dir < 0
+		})
+		return nil
+	}
+
+	fields, ok := asDocument(spec)
+	if !ok || len(fields) == 0 {
+		return errors.New("$sort must be 1, -1 or a document")
+	}
+	for _, f := range fields {
+		if dir, ok := asInt(f.Value); !ok || (dir != 1 && dir != -1) {
+			return fmt.Errorf("$sort %s must be 1 or -1", f.Key)
+		}
+	}
+
+	sort.SliceStable(arr, func(i, j int) bool {
+		for _, f := range fields {
+			dir, _ := asInt(f.Value)
+			a, _ := GetPath(arr[i], f.Key)
+			b, _ := GetPath(arr[j], f.Key)
+			if c := Compare(a, b); c != 0 {
+				return c*dir < 0
+			}
+		}
+		return false
+	})
+	return nil
+}
+
+func containsValue(arr A, v any) bool {
+	for _, elem := range arr {
+		if sameBracket(elem, v) && Compare(elem, v) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// pullMatcher returns a function that reports whether an array element must be removed.
+func pullMatcher(cond any) (func(v any) bool, error) {
+	if doc, ok := asDocument(cond); ok {
+		return compileElemMatch(doc)
+	}
+	if !isRegex(cond) {
+		// other values are compared with elements as a whole, nested arrays are not traversed.
+		return func(v any) bool { return sameBracket(v, cond) && Compare(v, cond) == 0 }, nil
+	}
+
+	m, err := compileCondition(cond)
+	if err != nil {
+		return nil, err
+	}
+	return func(v any) bool { return m([]any{v}) }, nil
+}
@@ -0,0 +1,188 @@
+package bson
+
+import (
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/cristalhq/bson/bsonproto"
+)
+
+func TestApplyUpdate(t *testing.T) {
+	now := time.Date(2023, 8, 10, 18, 5, 46, 123456789, time.UTC)
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = time.Now })
+
+	doc := D{
+		{"_id", int32(1)},
+		{"name", "john"},
+		{"visits", int32(10)},
+		{"score", 7.5},
+		{"dec", decimal(75, -1)},
+		{"address", D{{"city", "Berlin"}, {"zip", "10115"}}},
+		{"tags", A{"go", "mongo"}},
+		{"nums", A{int32(5), int32(1), int32(3)}},
+		{"nested", A{A{int32(1), int32(2)}, int32(1), int32(3)}},
+		{"items", A{
+			D{{"sku", "a"}, {"qty", int32(2)}},
+			D{{"sku", "b"}, {"qty", int32(10)}},
+		}},
+	}
+
+	testCases := []struct {
+		update D
+		path   string
+		want   any
+	}{
+		{D{{"$set", D{{"name", "bob"}}}}, "name", "bob"},
+		{D{{"$set", D{{"address.city", "Paris"}}}}, "address", D{{"city", "Paris"}, {"zip", "10115"}}},
+		{D{{"$set", D{{"new.field", int32(1)}}}}, "new", D{{"field", int32(1)}}},
+		{D{{"$set", D{{"tags.1", "sql"}}}}, "tags", A{"go", "sql"}},
+
+		{D{{"$inc", D{{"visits", int32(5)}}}}, "visits", int32(15)},
+		{D{{"$inc", D{{"visits", int64(5)}}}}, "visits", int64(15)},
+		{D{{"$inc", D{{"visits", int32(2147483647)}}}}, "visits", int64(2147483657)},
+		{D{{"$inc", D{{"score", int32(1)}}}}, "score", 8.5},
+		{D{{"$inc", D{{"missing", int32(3)}}}}, "missing", int32(3)},
+		{D{{"$mul", D{{"visits", int32(3)}}}}, "visits", int32(30)},
+		{D{{"$mul", D{{"score", 2.0}}}}, "score", 15.0},
+		{D{{"$mul", D{{"missing", int64(3)}}}}, "missing", int64(0)},
+		{D{{"$inc", D{{"dec", int32(1)}}}}, "dec", decimal(85, -1)},
+		{D{{"$inc", D{{"visits", decimal(5, -1)}}}}, "visits", decimal(105, -1)},
+		{D{{"$inc", D{{"score", decimal(1, 0)}}}}, "score", decimal(850000000000000, -14)},
+		{D{{"$mul", D{{"dec", decimal(-2, 0)}}}}, "dec", decimal(-150, -1)},
+		{D{{"$mul", D{{"missing", decimal(3, 0)}}}}, "missing", decimal(0, 0)},
+
+		{D{{"$min", D{{"visits", int32(3)}}}}, "visits", int32(3)},
+		{D{{"$min", D{{"visits", int32(30)}}}}, "visits", int32(10)},
+		{D{{"$max", D{{"visits", 30.5}}}}, "visits", 30.5},
+		{D{{"$max", D{{"missing", "x"}}}}, "missing", "x"},
+
+		{D{{"$rename", D{{"name", "user.name"}}}}, "user", D{{"name", "john"}}},
+
+		{D{{"$currentDate", D{{"updated", true}}}}, "updated", now.Truncate(time.Millisecond)},
+		{D{{"$currentDate", D{{"updated", D{{"$type", "timestamp"}}}}}}, "updated", NewTimestampWithCounter(now, 0)},
+
+		{D{{"$push", D{{"tags", "bson"}}}}, "tags", A{"go", "mongo", "bson"}},
+		{D{{"$push", D{{"missing", "x"}}}}, "missing", A{"x"}},
+		{D{{"$push", D{{"tags", A{"a", "b"}}}}}, "tags", A{"go", "mongo", A{"a", "b"}}},
+		{D{{"$push", D{{"tags", D{{"$each", A{"a", "b"}}}}}}}, "tags", A{"go", "mongo", "a", "b"}},
+		{D{{"$push", D{{"tags", D{{"$each", A{"a"}}, {"$position", int32(0)}}}}}}, "tags", A{"a", "go", "mongo"}},
+		{D{{"$push", D{{"nums", D{{"$each", A{int32(4)}}, {"$sort", int32(1)}}}}}}, "nums", A{int32(1), int32(3), int32(4), int32(5)}},
+		{D{{"$push", D{{"nums", D{{"$each", A{int32(4)}}, {"$sort", int32(-1)}, {"$slice", int32(2)}}}}}}, "nums", A{int32(5), int32(4)}},
+		{D{{"$push", D{{"nums", D{{"$each", A{}}, {"$slice", int32(-1)}}}}}}, "nums", A{int32(3)}},
+		{
+			D{{"$push", D{{"items", D{{"$each", A{D{{"sku", "c"}, {"qty", int32(5)}}}}, {"$sort", D{{"qty", int32(-1)}}}}}}}},
+			"items",
+			A{
+				D{{"sku", "b"}, {"qty", int32(10)}},
+				D{{"sku", "c"}, {"qty", int32(5)}},
+				D{{"sku", "a"}, {"qty", int32(2)}},
+			},
+		},
+
+		{D{{"$addToSet", D{{"tags", "go"}}}}, "tags", A{"go", "mongo"}},
+		{D{{"$addToSet", D{{"tags", "sql"}}}}, "tags", A{"go", "mongo", "sql"}},
+		{D{{"$addToSet", D{{"tags", D{{"$each", A{"go", "x", "x"}}}}}}}, "tags", A{"go", "mongo", "x"}},
+		{D{{"$addToSet", D{{"nums", 5.0}}}}, "nums", A{int32(5), int32(1), int32(3)}},
+
+		{D{{"$pull", D{{"tags", "go"}}}}, "tags", A{"mongo"}},
+		{D{{"$pull", D{{"nested", int32(1)}}}}, "nested", A{A{int32(1), int32(2)}, int32(3)}},
+		{D{{"$pull", D{{"nested", A{1.0, int64(2)}}}}}, "nested", A{int32(1), int32(3)}},
+		{D{{"$pull", D{{"nums", D{{"$gte", int32(3)}}}}}}, "nums", A{int32(1)}},
+		{D{{"$pull", D{{"items", D{{"qty", D{{"$gt", int32(5)}}}}}}}}, "items", A{D{{"sku", "a"}, {"qty", int32(2)}}}},
+
+		{D{{"$pop", D{{"tags", int32(1)}}}}, "tags", A{"go"}},
+		{D{{"$pop", D{{"tags", int32(-1)}}}}, "tags", A{"mongo"}},
+	}
+
+	for _, tc := range testCases {
+		res, err := ApplyUpdate(doc, tc.update)
+		mustOk(t, err)
+
+		have, err := GetPath(res, tc.path)
+		mustOk(t, err)
+		mustDeepEqual(t, have, tc.want)
+	}
+}
+
+func TestApplyUpdateDecimal(t *testing.T) {
+	doc := D{{"tiny", decimal(1, -30)}, {"big", decimal(9999999999999999, 6095)}}
+
+	res, err := ApplyUpdate(doc, D{{"$inc", D{{"tiny", int32(1)}}}})
+	mustOk(t, err)
+	want, _ := newDecimal(new(big.Int).Add(new(big.Int).Exp(big.NewInt(10), big.NewInt(30), nil), big.NewInt(1)), -30)
+	mustEqual(t, res[0].Value.(bsonproto.Decimal128), want)
+	mustEqual(t, Compare(res[0].Value, int32(1)), 1)
+
+	res, err = ApplyUpdate(doc, D{{"$mul", D{{"tiny", math.Inf(-1)}}}})
+	mustOk(t, err)
+	mustEqual(t, Compare(res[0].Value, math.Inf(-1)), 0)
+
+	_, err = ApplyUpdate(doc, D{{"$mul", D{{"big", decimal(1, 6000)}}}})
+	mustFail(t, err)
+}
+
+func TestApplyUpdateOrder(t *testing.T) {
+	doc := D{{"a", int32(1)}, {"b", int32(2)}, {"c", int32(3)}}
+	orig := D{{"a", int32(1)}, {"b", int32(2)}, {"c", int32(3)}}
+
+	res, err := ApplyUpdate(doc, D{
+		{"$set", D{{"z", int32(0)}, {"b", "x"}, {"y", int32(0)}}},
+		{"$unset", D{{"a", ""}}},
+	})
+	mustOk(t, err)
+	mustDeepEqual(t, res, D{{"b", "x"}, {"c", int32(3)}, {"y", int32(0)}, {"z", int32(0)}})
+	mustDeepEqual(t, doc, orig)
+}
+
+func TestApplyUpdateNoAliasing(t *testing.T) {
+	doc := D{{"tags", A{"go"}}}
+	value := A{"x"}
+
+	res, err := ApplyUpdate(doc, D{{"$set", D{{"list", value}}}, {"$push", D{{"tags", "bson"}}}})
+	mustOk(t, err)
+	mustDeepEqual(t, doc, D{{"tags", A{"go"}}})
+
+	value[0] = "changed"
+	mustDeepEqual(t, res, D{{"tags", A{"go", "bson"}}, {"list", A{"x"}}})
+}
+
+func TestApplyUpdateErrors(t *testing.T) {
+	doc := D{{"name", "john"}, {"n", int64(9223372036854775807)}, {"address", D{{"city", "Berlin"}}}}
+
+	updates := []D{
+		{{"name", "bob"}},
+		{{"$foo", D{{"a", int32(1)}}}},
+		{{"$set", "x"}},
+		{{"$set", D{{"a..b", int32(1)}}}},
+		{{"$set", D{{"a", int32(1)}}}, {"$inc", D{{"a", int32(1)}}}},
+		{{"$set", D{{"address", D{}}}}, {"$unset", D{{"address.city", ""}}}},
+		{{"$rename", D{{"name", int32(1)}}}},
+		{{"$rename", D{{"name", "address.city"}}}, {"$set", D{{"address", int32(1)}}}},
+		{{"$inc", D{{"name", int32(1)}}}},
+		{{"$inc", D{{"n", int32(1)}}}},
+		{{"$inc", D{{"a", "x"}}}},
+		{{"$mul", D{{"n", int32(2)}}}},
+		{{"$push", D{{"name", "x"}}}},
+		{{"$push", D{{"list", D{{"$each", "x"}}}}}},
+		{{"$push", D{{"list", D{{"$each", A{}}, {"$sort", int32(2)}}}}}},
+		{{"$addToSet", D{{"name", "x"}}}},
+		{{"$pull", D{{"name", "x"}}}},
+		{{"$pop", D{{"name", int32(1)}}}},
+		{{"$pop", D{{"list", int32(2)}}}},
+		{{"$set", D{{"name.first", "x"}}}},
+		{{"$set", D{{"x", RawObject{1, 2}}}}},
+		{{"$max", D{{"name", make(chan int)}}}},
+	}
+
+	for _, update := range updates {
+		if _, err := ApplyUpdate(doc, update); err == nil {
+			t.Fatalf("update %v must fail", update)
+		}
+	}
+
+	_, err := ApplyUpdate(D{{"x", RawArray{1}}}, D{{"$set", D{{"a", int32(1)}}}})
+	mustFail(t, err)
+}
@@ -0,0 +1,69 @@
+package bson
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// UTF8Mode defines how strings and element names with invalid UTF-8 are handled.
+type UTF8Mode int
+
+const (
+	// UTF8Ignore passes strings as is, this is the default.
+	UTF8Ignore UTF8Mode = iota
+
+	// UTF8Strict returns [ErrInvalidUTF8] for invalid UTF-8.
+	UTF8Strict
+
+	// UTF8Replace replaces invalid UTF-8 sequences with U+FFFD.
+	UTF8Replace
+)
+
+var (
+	// ErrInvalidUTF8 is returned in [UTF8Strict] mode for strings and element names with invalid UTF-8.
+	ErrInvalidUTF8 = errors.New("bson: invalid UTF-8")
+
+	// ErrInvalidElementName is returned when an element name contains 0x00.
+	ErrInvalidElementName = errors.New("bson: element name contains 0x00")
+)
+
+// SetUTF8Mode sets how invalid UTF-8 in encoded strings and element names is handled.
+func (enc *Encoder) SetUTF8Mode(mode UTF8Mode) {
+	enc.utf8 = mode
+}
+
+// SetUTF8Mode sets how invalid UTF-8 in decoded strings and element names is handled.
+func (dec *Decoder) SetUTF8Mode(mode UTF8Mode) {
+	dec.utf8 = mode
+}
+
+// elemName validates the element name before encoding.
+func (enc *Encoder) elemName(name string) (string, error) {
+	if strings.IndexByte(name, 0) != -1 {
+		return "", fmt.Errorf("%w: %q", ErrInvalidElementName, name)
+	}
+	return checkUTF8(enc.utf8, name)
+}
+
+// key returns the name of the element with trailing 0x00 trimmed.
+func (ds *decodeState) key(name []byte) (string, error) {
+	return checkUTF8(ds.utf8, trimlast(name))
+}
+
+// str returns the string element without trailing 0x00.
+func (ds *decodeState) str(element []byte) (string, error) {
+	return checkUTF8(ds.utf8, trimlast(element))
+}
+
+func checkUTF8(mode UTF8Mode, s string) (string, error) {
+	switch {
+	case mode == UTF8Ignore || utf8.ValidString(s):
+		return s, nil
+	case mode == UTF8Replace:
+		return strings.ToValidUTF8(s, string(utf8.RuneError)), nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidUTF8, s)
+	}
+}
@@ -0,0 +1,76 @@
+package bson
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncodeElementName(t *testing.T) {
+	_, err := Marshal(D{{"a\x00b", int32(1)}})
+	mustEqual(t, errors.Is(err, ErrInvalidElementName), true)
+
+	_, err = Marshal(D{{"a", D{{"\x00", int32(1)}}}})
+	mustEqual(t, errors.Is(err, ErrInvalidElementName), true)
+
+	_, err = Marshal(map[string]int{"\x00": 1})
+	mustEqual(t, errors.Is(err, ErrInvalidElementName), true)
+}
+
+func TestEncodeUTF8(t *testing.T) {
+	doc := D{{"a\xff", "b\xffc"}}
+
+	got, err := Marshal(doc)
+	mustOk(t, err)
+	wantBytes(t, got, "110000000261ff000400000062ff630000")
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetUTF8Mode(UTF8Strict)
+	mustOk(t, enc.Encode(D{{"ключ", "значение"}}))
+	err = enc.Encode(doc)
+	mustEqual(t, errors.Is(err, ErrInvalidUTF8), true)
+	err = enc.Encode(D{{"a", A{"\xff"}}})
+	mustEqual(t, errors.Is(err, ErrInvalidUTF8), true)
+
+	buf.Reset()
+	enc.SetUTF8Mode(UTF8Replace)
+	mustOk(t, enc.Encode(doc))
+	mustDeepEqual(t, buf.Bytes(), must(Marshal(D{{"a�", "b�c"}})))
+}
+
+func TestDecodeUTF8(t *testing.T) {
+	raw := must(Marshal(D{{"a", D{{"b\xff", "c"}, {"d", "e\xfff"}}}}))
+
+	var d D
+	mustOk(t, Unmarshal(raw, &d))
+	mustDeepEqual(t, d, D{{"a", map[string]any{"b\xff": "c", "d": "e\xfff"}}})
+
+	dec := NewDecodeBytes(raw)
+	dec.SetUTF8Mode(UTF8Strict)
+	err := dec.Decode(&d)
+	mustEqual(t, errors.Is(err, ErrInvalidUTF8), true)
+
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("want DecodeError, got %v", err)
+	}
+	mustEqual(t, de.Path, "a.b\xff")
+
+	type record struct {
+		A struct {
+			D string `bson:"d"`
+		} `bson:"a"`
+	}
+	var r record
+	dec = NewDecodeBytes(must(Marshal(D{{"a", D{{"d", "e\xfff"}}}})))
+	dec.SetUTF8Mode(UTF8Strict)
+	err = dec.Decode(&r)
+	mustEqual(t, errors.Is(err, ErrInvalidUTF8), true)
+
+	var m map[string]any
+	dec = NewDecodeBytes(raw)
+	dec.SetUTF8Mode(UTF8Replace)
+	mustOk(t, dec.Decode(&m))
+	mustDeepEqual(t, m, map[string]any{"a": map[string]any{"b�": "c", "d": "e�f"}})
+}
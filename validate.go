@@ -0,0 +1,116 @@
+package bson
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/cristalhq/bson/bsonproto"
+)
+
+// ValidateOptions configures [Validate].
+type ValidateOptions struct {
+	// UTF8 checks that element names and strings are valid UTF-8.
+	UTF8 bool
+
+	// MaxDepth is the max nesting depth of documents and arrays, [DefaultMaxDepth] if zero.
+	MaxDepth int
+}
+
+// Validate checks that data is a well-formed BSON document without decoding it into Go values.
+// Lengths, terminators and values of all element types are checked,
+// keys of arrays must be "0", "1", "2" and so on.
+// Nil options are the same as zero [ValidateOptions].
+// The returned error is a [*DecodeError].
+func Validate(data []byte, opts *ValidateOptions) error {
+	var o ValidateOptions
+	if opts != nil {
+		o = *opts
+	}
+	return validateDocument(data, &o, 1, false)
+}
+
+func validateDocument(data []byte, opts *ValidateOptions, depth int, array bool) error {
+	if max := limit(opts.MaxDepth, DefaultMaxDepth); depth > max {
+		return &DecodeError{Err: fmt.Errorf("%w: max depth is %d", ErrMaxDepthExceeded, max)}
+	}
+
+	iter, err := newReader(data)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; iter.Next(); i++ {
+		typ, name, element := iter.Peek()
+
+		if err := validateName(name, opts, array, i); err != nil {
+			return iter.wrapErr(err)
+		}
+		if err := validateElement(typ, element, opts, depth); err != nil {
+			return iter.wrapErr(err)
+		}
+	}
+	return iter.Err()
+}
+
+// validateName checks the element name with trailing 0x00.
+func validateName(name []byte, opts *ValidateOptions, array bool, i int) error {
+	name = name[:len(name)-1]
+
+	if array {
+		var buf [20]byte
+		if !bytes.Equal(name, strconv.AppendInt(buf[:0], int64(i), 10)) {
+			return fmt.Errorf("%w: array key must be %d", ErrInvalidInput, i)
+		}
+		return nil
+	}
+	if opts.UTF8 && !utf8.Valid(name) {
+		return fmt.Errorf("%w: element name is not valid UTF-8", ErrInvalidInput)
+	}
+	return nil
+}
+
+// validateElement checks the element framed by the reader.
+func validateElement(typ Type, element []byte, opts *ValidateOptions, depth int) error {
+	switch typ {
+	case TypeString, TypeCodeWithScope, TypeSymbol, TypeRegex:
+		return validateUTF8(element[:len(element)-1], opts)
+
+	case TypeDocument, TypeArray:
+		return validateDocument(element, opts, depth+1, typ == TypeArray)
+
+	case TypeDBPointer:
+		return validateUTF8(element[4:len(element)-12-1], opts)
+
+	case TypeJavaScriptScope:
+		n, _ := readInt32(element[4:])
+		if err := validateUTF8(element[8:8+n-1], opts); err != nil {
+			return err
+		}
+		err := validateDocument(element[8+n:], opts, depth+1, false)
+		if de, ok := err.(*DecodeError); ok {
+			de.Offset += 8 + n
+		}
+		return err
+
+	case TypeBinary:
+		if bsonproto.BinarySubtype(element[4]) != bsonproto.BinaryGenericOld {
+			return nil
+		}
+		if len(element) < 9 {
+			return fmt.Errorf("%w: old binary is too short", ErrInvalidInput)
+		}
+		if n, _ := readInt32(element[5:]); n != len(element)-9 {
+			return fmt.Errorf("%w: old binary length %d, have %d bytes", ErrInvalidInput, n, len(element)-9)
+		}
+	}
+	return nil
+}
+
+func validateUTF8(s []byte, opts *ValidateOptions) error {
+	if opts.UTF8 && !utf8.Valid(s) {
+		return fmt.Errorf("%w: string is not valid UTF-8", ErrInvalidInput)
+	}
+	return nil
+}
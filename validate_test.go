@@ -0,0 +1,102 @@
+package bson
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cristalhq/bson/bsonproto"
+)
+
+func TestValidate(t *testing.T) {
+	// doc wraps hex encoded elements into a document with a valid size and terminator.
+	doc := func(body string) []byte {
+		b := unhex(body)
+		n := len(b) + 5
+		return append(append([]byte{byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24)}, b...), 0)
+	}
+
+	all := must(Marshal(D{
+		{"double", 1.5},
+		{"string", "str"},
+		{"doc", D{{"a", int32(1)}}},
+		{"array", A{"a", A{int32(1)}, D{}}},
+		{"binary", []byte{1, 2, 3}},
+		{"old", bsonproto.Binary{Subtype: bsonproto.BinaryGenericOld, B: []byte{2, 0, 0, 0, 1, 2}}},
+		{"oid", NewObjectID()},
+		{"bool", true},
+		{"date", timeNow()},
+		{"null", nil},
+		{"regex", Regex{Pattern: "^a", Options: "i"}},
+		{"int32", int32(1)},
+		{"ts", Timestamp(1)},
+		{"int64", int64(1)},
+		{"dec", decimal(1, 0)},
+		{"min", MinKey{}},
+		{"max", MaxKey{}},
+	}))
+	mustOk(t, Validate(all, nil))
+	mustOk(t, Validate(all, &ValidateOptions{UTF8: true}))
+
+	valid := [][]byte{
+		doc("04610005000000" + "00"),               // empty array
+		doc("0461000c000000" + "1030000100000000"), // array
+		doc("066100"), // undefined
+		doc("0c6100" + "0200000061" + "00" + "0102030405060708090a0b0c"),                   // db pointer
+		doc("0d6100" + "020000006100"),                                                     // javascript
+		doc("0e6100" + "020000006100"),                                                     // symbol
+		doc("0f6100" + "16000000" + "020000006100" + "0c000000" + "10620001000000" + "00"), // code with scope
+	}
+	for _, data := range valid {
+		if err := Validate(data, &ValidateOptions{UTF8: true}); err != nil {
+			t.Fatalf("%x: %v", data, err)
+		}
+	}
+
+	testCases := []struct {
+		data []byte
+		opts *ValidateOptions
+		path string
+	}{
+		{data: unhex("0400000000")},
+		{data: doc("026100020000006161")},
+		{data: doc("08610002"), path: "a"},
+		{data: doc("0461000c000000" + "1031000100000000"), path: "a.1"},
+		{data: doc("0461001400000010300001000000103000020000000000"), path: "a.0"},
+		{data: doc("0561000600000002" + "050000000102"), path: "a"},
+		{data: doc("0f6100" + "13000000" + "020000006100" + "09000000" + "08620002" + "00"), path: "a.b"},
+		{data: doc("0261000300000061ff00"), opts: &ValidateOptions{UTF8: true}, path: "a"},
+		{data: doc("02ff00020000006100"), opts: &ValidateOptions{UTF8: true}, path: "\xff"},
+		{data: doc("0b6100ff0000"), opts: &ValidateOptions{UTF8: true}, path: "a"},
+		{data: doc("03610005000000" + "00"), opts: &ValidateOptions{MaxDepth: 1}, path: "a"},
+	}
+
+	for _, tc := range testCases {
+		err := Validate(tc.data, tc.opts)
+		var de *DecodeError
+		if !errors.As(err, &de) {
+			t.Fatalf("%x: want DecodeError, got %v", tc.data, err)
+		}
+		if tc.path != "" {
+			mustEqual(t, de.Path, tc.path)
+		}
+	}
+
+	// invalid UTF-8 is allowed by default.
+	mustOk(t, Validate(doc("0261000300000061ff00"), nil))
+}
+
+func TestValidateAllocs(t *testing.T) {
+	data := must(Marshal(D{
+		{"a", A{int32(1), "x", D{{"b", A{1.5}}}}},
+		{"s", "str"},
+		{"re", Regex{Pattern: "a", Options: "i"}},
+	}))
+	opts := &ValidateOptions{UTF8: true}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := Validate(data, opts); err != nil {
+			t.Fatal(err)
+		}
+	})
+	mustEqual(t, allocs, 0.0)
+}